@@ -0,0 +1,67 @@
+// Package asciicast records play shells as asciinema v2 files, which can be
+// played back with asciinema or the replay package.
+package asciicast
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const Version = 2
+
+// The kind of an event in a recording
+type Code string
+
+const (
+	Output Code = "o"
+	Input  Code = "i"
+	Marker Code = "m"
+	Resize Code = "r"
+)
+
+// The first line of a recording
+type Header struct {
+	Version       int               `json:"version"`
+	Width         int               `json:"width"`
+	Height        int               `json:"height"`
+	Timestamp     int64             `json:"timestamp,omitempty"`
+	IdleTimeLimit float64           `json:"idle_time_limit,omitempty"`
+	Title         string            `json:"title,omitempty"`
+	Env           map[string]string `json:"env,omitempty"`
+}
+
+// A line of a recording after the header, stored as [time, code, data]
+type Event struct {
+	// Since the start of the recording
+	Time time.Duration
+	Code Code
+	Data string
+}
+
+func (e Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{e.Time.Seconds(), e.Code, e.Data})
+}
+
+func (e *Event) UnmarshalJSON(raw []byte) error {
+	var fields []json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return err
+	}
+	if len(fields) != 3 {
+		return fmt.Errorf("event has %d fields, expected 3", len(fields))
+	}
+
+	var seconds float64
+	if err := json.Unmarshal(fields[0], &seconds); err != nil {
+		return fmt.Errorf("event time: %w", err)
+	}
+	if err := json.Unmarshal(fields[1], &e.Code); err != nil {
+		return fmt.Errorf("event code: %w", err)
+	}
+	if err := json.Unmarshal(fields[2], &e.Data); err != nil {
+		return fmt.Errorf("event data: %w", err)
+	}
+	e.Time = time.Duration(seconds * float64(time.Second))
+	return nil
+}
@@ -0,0 +1,12 @@
+package asciicast
+
+import "github.com/srepio/sdk/internal/redact"
+
+var (
+	// Anything that looks like a credential. When a pattern has a group
+	// named secret only that part of the match is masked.
+	DefaultSecrets = redact.Secrets
+	// Output that asks for something secret, the next line of input is
+	// masked entirely
+	DefaultPrompts = redact.Prompts
+)
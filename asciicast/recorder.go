@@ -0,0 +1,233 @@
+package asciicast
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/srepio/sdk/client"
+	"github.com/srepio/sdk/internal/redact"
+	"github.com/srepio/sdk/types"
+)
+
+// How much of the current line is kept to spot prompts, and held back at
+// most before it is masked and recorded
+const promptWindow = 256
+
+type Options struct {
+	// The initial terminal size, defaults to 80x24
+	Width  int
+	Height int
+	Title  string
+	Env    map[string]string
+	// When the recording started, defaults to now
+	Start time.Time
+	// Patterns masked in input and output, defaults to DefaultSecrets
+	Secrets []*regexp.Regexp
+	// Output that makes the next line of input secret, defaults to
+	// DefaultPrompts
+	Prompts []*regexp.Regexp
+	// Record everything as is
+	NoMasking bool
+}
+
+// Writes a shell session as an asciicast v2 recording. Output and resizes are
+// recorded as they are, input is recorded as markers so that players show
+// it without typing it again. It implements client.ShellRecorder.
+//
+// Input and output are held back until the end of the line, or promptWindow
+// bytes, so a secret typed or echoed a keystroke at a time is masked as a
+// whole. Close writes out whatever is still held back.
+type Recorder struct {
+	mu     *sync.Mutex
+	enc    *json.Encoder
+	opts   *Options
+	line   string
+	secret bool
+	// Input and output waiting for the end of the line
+	typed string
+	echo  string
+	last  time.Duration
+	err   error
+}
+
+var _ client.ShellRecorder = (*Recorder)(nil)
+
+// Start a recording, the header is written straight away
+func NewRecorder(w io.Writer, opts *Options) (*Recorder, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	o := *opts
+	if o.Width <= 0 {
+		o.Width = 80
+	}
+	if o.Height <= 0 {
+		o.Height = 24
+	}
+	if o.Start.IsZero() {
+		o.Start = time.Now()
+	}
+	if o.Secrets == nil {
+		o.Secrets = DefaultSecrets
+	}
+	if o.Prompts == nil {
+		o.Prompts = DefaultPrompts
+	}
+
+	r := &Recorder{
+		mu:   &sync.Mutex{},
+		enc:  json.NewEncoder(w),
+		opts: &o,
+	}
+	r.enc.SetEscapeHTML(false)
+
+	err := r.enc.Encode(Header{
+		Version:   Version,
+		Width:     o.Width,
+		Height:    o.Height,
+		Timestamp: o.Start.Unix(),
+		Title:     o.Title,
+		Env:       o.Env,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Recorder) RecordEvent(at time.Time, p types.Payload) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	elapsed := at.Sub(r.opts.Start)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	if elapsed > r.last {
+		r.last = elapsed
+	}
+
+	switch p := p.(type) {
+	case *types.OutputPayload:
+		r.output(elapsed, string(p.Data))
+	case *types.InputPayload:
+		r.input(elapsed, string(p.Data))
+	case *types.ResizePayload:
+		// The size is sent as <cols>x<rows>
+		r.write(Event{Time: elapsed, Code: Resize, Data: fmt.Sprintf("%dx%d", p.Cols, p.Rows)})
+	}
+}
+
+// The first error hit writing the recording, recording stops after it
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Write out input and output held back waiting for the end of a line, it is
+// recorded at the time of the last event. Returns the first error hit writing
+// the recording.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.flushOutput(r.last)
+	if r.typed != "" && !r.secret {
+		r.write(Event{Time: r.last, Code: Marker, Data: r.mask(r.typed)})
+	}
+	r.typed = ""
+	return r.err
+}
+
+func (r *Recorder) output(at time.Duration, data string) {
+	if r.opts.NoMasking {
+		r.write(Event{Time: at, Code: Output, Data: data})
+		return
+	}
+
+	if i := strings.LastIndexAny(data, "\r\n"); i >= 0 {
+		r.line = data[i+1:]
+	} else {
+		r.line += data
+	}
+	if len(r.line) > promptWindow {
+		r.line = r.line[len(r.line)-promptWindow:]
+	}
+
+	r.echo += data
+	if i := strings.LastIndexAny(r.echo, "\r\n"); i >= 0 {
+		r.write(Event{Time: at, Code: Output, Data: r.mask(r.echo[:i+1])})
+		r.echo = r.echo[i+1:]
+	}
+	// Prompts are shown straight away rather than once they are answered
+	if redact.Prompted(r.line, r.opts.Prompts) {
+		r.secret = true
+		r.flushOutput(at)
+	} else if len(r.echo) > promptWindow {
+		r.flushOutput(at)
+	}
+}
+
+func (r *Recorder) flushOutput(at time.Duration) {
+	if r.echo == "" {
+		return
+	}
+	r.write(Event{Time: at, Code: Output, Data: r.mask(r.echo)})
+	r.echo = ""
+}
+
+// Input is recorded a line at a time, after the output echoed so far. A line
+// that answers a secret prompt is recorded as a single mask.
+func (r *Recorder) input(at time.Duration, data string) {
+	if r.opts.NoMasking {
+		r.write(Event{Time: at, Code: Marker, Data: data})
+		return
+	}
+
+	r.typed += data
+	for {
+		i := strings.IndexAny(r.typed, "\r\n")
+		if i < 0 {
+			break
+		}
+		line := r.typed[:i+1]
+		r.typed = r.typed[i+1:]
+
+		r.flushOutput(at)
+		if r.secret {
+			r.secret = false
+			r.line = ""
+			r.write(Event{Time: at, Code: Marker, Data: redact.Marker + line[i:]})
+			continue
+		}
+		r.write(Event{Time: at, Code: Marker, Data: r.mask(line)})
+	}
+
+	if len(r.typed) > promptWindow {
+		if !r.secret {
+			r.flushOutput(at)
+			r.write(Event{Time: at, Code: Marker, Data: r.mask(r.typed)})
+		}
+		r.typed = ""
+	}
+}
+
+func (r *Recorder) mask(data string) string {
+	if r.opts.NoMasking {
+		return data
+	}
+	return redact.Text(data, r.opts.Secrets)
+}
+
+func (r *Recorder) write(ev Event) {
+	if r.err != nil {
+		return
+	}
+	r.err = r.enc.Encode(ev)
+}
@@ -0,0 +1,140 @@
+package asciicast
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/srepio/sdk/client"
+	"github.com/srepio/sdk/internal/redact"
+	"github.com/srepio/sdk/srepfake"
+	"github.com/srepio/sdk/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parse(t *testing.T, raw []byte) (Header, []Event) {
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	require.True(t, scanner.Scan())
+	var header Header
+	require.Nil(t, json.Unmarshal(scanner.Bytes(), &header))
+
+	var events []Event
+	for scanner.Scan() {
+		var ev Event
+		require.Nil(t, json.Unmarshal(scanner.Bytes(), &ev))
+		events = append(events, ev)
+	}
+	return header, events
+}
+
+func TestRecorder(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	at := func(ms int) time.Time {
+		return start.Add(time.Duration(ms) * time.Millisecond)
+	}
+
+	buf := &bytes.Buffer{}
+	rec, err := NewRecorder(buf, &Options{Start: start, Title: "mango"})
+	require.Nil(t, err)
+
+	rec.RecordEvent(at(0), &types.ResizePayload{Rows: 40, Cols: 120})
+	rec.RecordEvent(at(100), &types.InputPayload{Data: []byte("ls\n")})
+	rec.RecordEvent(at(150), &types.OutputPayload{Data: []byte("ls\r\nfile\r\n")})
+	rec.RecordEvent(at(200), &types.OutputPayload{Data: []byte("[sudo] password for bongo: ")})
+	rec.RecordEvent(at(300), &types.InputPayload{Data: []byte("hun")})
+	rec.RecordEvent(at(400), &types.InputPayload{Data: []byte("ter2\rwhoami\n")})
+	rec.RecordEvent(at(500), &types.OutputPayload{Data: []byte("Authorization: Bearer abcdefghijkl\r\n")})
+	rec.RecordEvent(at(600), &types.InputPayload{Data: []byte("export API_KEY=hunter2\n")})
+	require.Nil(t, rec.Err())
+
+	header, events := parse(t, buf.Bytes())
+	assert.Equal(t, Header{Version: 2, Width: 80, Height: 24, Timestamp: start.Unix(), Title: "mango"}, header)
+	assert.Equal(t, []Event{
+		{Time: 0, Code: Resize, Data: "120x40"},
+		{Time: 100 * time.Millisecond, Code: Marker, Data: "ls\n"},
+		{Time: 150 * time.Millisecond, Code: Output, Data: "ls\r\nfile\r\n"},
+		{Time: 200 * time.Millisecond, Code: Output, Data: "[sudo] password for bongo: "},
+		{Time: 400 * time.Millisecond, Code: Marker, Data: redact.Marker + "\r"},
+		{Time: 400 * time.Millisecond, Code: Marker, Data: "whoami\n"},
+		{Time: 500 * time.Millisecond, Code: Output, Data: "Authorization: Bearer " + redact.Marker + "\r\n"},
+		{Time: 600 * time.Millisecond, Code: Marker, Data: "export API_KEY=" + redact.Marker + "\n"},
+	}, events)
+}
+
+func TestRecorderMasksSecretsTypedAKeyAtATime(t *testing.T) {
+	buf := &bytes.Buffer{}
+	rec, err := NewRecorder(buf, nil)
+	require.Nil(t, err)
+
+	// Each keystroke is echoed back as it is typed
+	for _, c := range "export API_KEY=hunter2" {
+		rec.RecordEvent(time.Now(), &types.InputPayload{Data: []byte(string(c))})
+		rec.RecordEvent(time.Now(), &types.OutputPayload{Data: []byte(string(c))})
+	}
+	rec.RecordEvent(time.Now(), &types.InputPayload{Data: []byte("\r")})
+	rec.RecordEvent(time.Now(), &types.OutputPayload{Data: []byte("\r\n$ ")})
+	require.Nil(t, rec.Close())
+
+	raw := buf.String()
+	assert.NotContains(t, raw, "hunter2")
+	_, events := parse(t, buf.Bytes())
+	require.Len(t, events, 4)
+	assert.Equal(t, Event{Time: events[0].Time, Code: Output, Data: "export API_KEY=" + redact.Marker}, events[0])
+	assert.Equal(t, Event{Time: events[1].Time, Code: Marker, Data: "export API_KEY=" + redact.Marker + "\r"}, events[1])
+	assert.Equal(t, Event{Time: events[2].Time, Code: Output, Data: "\r\n"}, events[2])
+	assert.Equal(t, Event{Time: events[3].Time, Code: Output, Data: "$ "}, events[3])
+}
+
+func TestRecorderWithoutMasking(t *testing.T) {
+	buf := &bytes.Buffer{}
+	rec, err := NewRecorder(buf, &Options{NoMasking: true})
+	require.Nil(t, err)
+
+	rec.RecordEvent(time.Now(), &types.OutputPayload{Data: []byte("Password: ")})
+	rec.RecordEvent(time.Now(), &types.InputPayload{Data: []byte("hunter2\n")})
+
+	_, events := parse(t, buf.Bytes())
+	require.Len(t, events, 2)
+	assert.Equal(t, "hunter2\n", events[1].Data)
+}
+
+func TestRecordingAShell(t *testing.T) {
+	s := srepfake.New(nil)
+	defer s.Close()
+	_, token := s.NewUser("Bongo", "bongo@srep.io", "hunter2hunter2")
+	c := client.NewClient(&client.ClientOptions{
+		Url:    s.Host(),
+		Scheme: "http",
+		Token:  token,
+	})
+	ctx := context.Background()
+	started, err := c.StartPlay(ctx, &client.StartPlayRequest{Scenario: "mango"})
+	require.Nil(t, err)
+
+	buf := &bytes.Buffer{}
+	rec, err := NewRecorder(buf, nil)
+	require.Nil(t, err)
+
+	shell, err := c.OpenShell(ctx, &client.GetShellRequest{ID: started.Play.ID, Rows: 30, Cols: 100}, &client.ShellOptions{
+		Recorder: rec,
+	})
+	require.Nil(t, err)
+	_, err = shell.Write([]byte("ls\n"))
+	require.Nil(t, err)
+	<-shell.Output()
+	shell.Close()
+	require.Nil(t, rec.Close())
+
+	_, events := parse(t, buf.Bytes())
+	require.Len(t, events, 3)
+	assert.Equal(t, Event{Time: events[0].Time, Code: Resize, Data: "100x30"}, events[0])
+	assert.Equal(t, Marker, events[1].Code)
+	assert.Equal(t, "ls\n", events[1].Data)
+	assert.Equal(t, Output, events[2].Code)
+	assert.Equal(t, "ls\n", events[2].Data)
+	assert.True(t, events[2].Time >= events[1].Time)
+}
@@ -0,0 +1,105 @@
+// Package cassette records the HTTP and websocket interactions made by a
+// client to redacted files and replays them deterministically, so tests can
+// run without network access.
+package cassette
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/srepio/sdk/types"
+)
+
+var (
+	ErrNoInteraction = errors.New("no matching interaction in cassette")
+)
+
+type Mode int
+
+const (
+	// Replay from the cassette, failing when an interaction is missing
+	ModeReplay Mode = iota
+	// Make real requests and record them to the cassette
+	ModeRecord
+	// Replay when the cassette file exists, otherwise record
+	ModeAuto
+)
+
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+	Sockets      []*Socket      `json:"sockets,omitempty"`
+}
+
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type Response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type Direction string
+
+const (
+	Sent     Direction = "sent"
+	Received Direction = "received"
+)
+
+// A websocket session, the handshake response and every frame in order
+type Socket struct {
+	URL      string    `json:"url"`
+	Response Response  `json:"response"`
+	Frames   []*Frame  `json:"frames,omitempty"`
+	Close    *CloseErr `json:"close,omitempty"`
+}
+
+type Frame struct {
+	Direction Direction          `json:"direction"`
+	Event     *types.SocketEvent `json:"event"`
+	// Since the socket was opened, used to replay sessions at their
+	// recorded pace
+	Time time.Duration `json:"time,omitempty"`
+}
+
+// How the server closed the socket
+type CloseErr struct {
+	Code int    `json:"code"`
+	Text string `json:"text,omitempty"`
+}
+
+func Load(path string) (*Cassette, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Cassette{}
+	if err := json.Unmarshal(raw, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Cassette) Save(path string) error {
+	raw, err := json.MarshalIndent(c, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(raw, '\n'), 0o644)
+}
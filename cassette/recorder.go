@@ -0,0 +1,254 @@
+package cassette
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/srepio/sdk/client"
+	"github.com/srepio/sdk/internal/redact"
+)
+
+type Options struct {
+	Mode Mode
+	// Used to make real requests when recording, defaults to http.DefaultTransport
+	Transport http.RoundTripper
+	// Used to open real sockets when recording, defaults to a websocket dialer
+	SocketDialer client.SocketDialer
+}
+
+// An http.RoundTripper and client.SocketDialer that records to or replays
+// from a cassette file. Credentials in headers, JSON bodies and shell traffic
+// are masked as they are recorded, but a secret typed a keystroke at a time
+// is only caught when it answers a prompt such as "Password:", so cassettes
+// should still be reviewed before they are committed.
+type Recorder struct {
+	path     string
+	mode     Mode
+	cassette *Cassette
+	next     http.RoundTripper
+	dialer   client.SocketDialer
+
+	mu      *sync.Mutex
+	used    map[*Interaction]bool
+	sockets int
+}
+
+func New(path string, opts *Options) (*Recorder, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	r := &Recorder{
+		path:     path,
+		mode:     opts.Mode,
+		cassette: &Cassette{},
+		next:     opts.Transport,
+		dialer:   opts.SocketDialer,
+		mu:       &sync.Mutex{},
+		used:     map[*Interaction]bool{},
+	}
+	if r.next == nil {
+		r.next = http.DefaultTransport
+	}
+	if r.dialer == nil {
+		r.dialer = client.NewWebsocketDialer(nil)
+	}
+
+	if r.mode == ModeAuto {
+		r.mode = ModeRecord
+		if _, err := os.Stat(path); err == nil {
+			r.mode = ModeReplay
+		}
+	}
+	if r.mode == ModeReplay {
+		c, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		r.cassette = c
+	}
+
+	return r, nil
+}
+
+// Whether the recorder is recording or replaying
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Point the client options at the recorder
+func (r *Recorder) Configure(opts *client.ClientOptions) *client.ClientOptions {
+	opts.Transport = r
+	opts.SocketDialer = r
+	return opts
+}
+
+// Write the cassette to disk when recording
+func (r *Recorder) Stop() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cassette.Save(r.path)
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	recorded := Request{
+		Method: req.Method,
+		URL:    req.URL.RequestURI(),
+		Header: redact.Header(req.Header),
+		Body:   string(redact.JSON(body)),
+	}
+
+	if r.mode == ModeReplay {
+		i, err := r.match(recorded)
+		if err != nil {
+			return nil, err
+		}
+		return i.Response.http(req), nil
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err = readBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, &Interaction{
+		Request: recorded,
+		Response: Response{
+			Status: resp.StatusCode,
+			Header: redact.Header(resp.Header),
+			Body:   string(redact.JSON(body)),
+		},
+	})
+
+	return resp, nil
+}
+
+// Find the first unused interaction for the request
+func (r *Recorder) match(req Request) (*Interaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, i := range r.cassette.Interactions {
+		if r.used[i] {
+			continue
+		}
+		if i.Request.Method == req.Method && i.Request.URL == req.URL && sameBody(i.Request.Body, req.Body) {
+			r.used[i] = true
+			return i, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, req.Method, req.URL)
+}
+
+func (r *Recorder) DialSocket(ctx context.Context, url string, header http.Header) (client.Socket, *http.Response, error) {
+	if r.mode == ModeReplay {
+		return r.replaySocket(url)
+	}
+
+	sock, resp, err := r.dialer.DialSocket(ctx, url, header)
+	if resp == nil {
+		return sock, resp, err
+	}
+
+	recorded := &Socket{
+		URL: requestURI(url),
+		Response: Response{
+			Status: resp.StatusCode,
+			Header: redact.Header(resp.Header),
+		},
+	}
+	r.mu.Lock()
+	r.cassette.Sockets = append(r.cassette.Sockets, recorded)
+	r.mu.Unlock()
+
+	if err != nil {
+		return sock, resp, err
+	}
+	return &recordingSocket{next: sock, redact: &redactor{}, rec: recorded, mu: r.mu, start: time.Now()}, resp, nil
+}
+
+func (r *Recorder) replaySocket(url string) (client.Socket, *http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	uri := requestURI(url)
+	for r.sockets < len(r.cassette.Sockets) {
+		s := r.cassette.Sockets[r.sockets]
+		r.sockets++
+		if s.URL != uri {
+			continue
+		}
+
+		resp := s.Response.http(nil)
+		if s.Response.Status != http.StatusSwitchingProtocols {
+			return nil, resp, errors.New("websocket: bad handshake")
+		}
+		return newReplaySocket(s), resp, nil
+	}
+
+	return nil, nil, fmt.Errorf("%w: socket %s", ErrNoInteraction, uri)
+}
+
+func (r Response) http(req *http.Request) *http.Response {
+	header := r.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.Status, http.StatusText(r.Status)),
+		StatusCode:    r.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewBufferString(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+	b, err := io.ReadAll(*body)
+	if err != nil {
+		return nil, err
+	}
+	(*body).Close()
+	*body = io.NopCloser(bytes.NewReader(b))
+	return b, nil
+}
+
+// Compares JSON bodies semantically, falling back to an exact match
+func sameBody(a, b string) bool {
+	if a == b {
+		return true
+	}
+	var av, bv any
+	if json.Unmarshal([]byte(a), &av) != nil || json.Unmarshal([]byte(b), &bv) != nil {
+		return false
+	}
+	return reflect.DeepEqual(av, bv)
+}
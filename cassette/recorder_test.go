@@ -0,0 +1,117 @@
+package cassette
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srepio/sdk/client"
+	"github.com/srepio/sdk/internal/redact"
+	"github.com/srepio/sdk/srepfake"
+	"github.com/srepio/sdk/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Runs the same script against a recording and a replaying client
+func script(t *testing.T, c *client.Client) {
+	ctx := context.Background()
+
+	started, err := c.StartPlay(ctx, &client.StartPlayRequest{Scenario: "mango"})
+	require.Nil(t, err)
+
+	stdinR, stdinW := pipe(t)
+	stdoutR, stdoutW := pipe(t)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- c.GetShell(ctx, &client.GetShellRequest{ID: started.Play.ID}, stdinR, stdoutW, nil)
+	}()
+
+	out := bufio.NewReader(stdoutR)
+	stdinW.Write([]byte("ls\n"))
+	line, err := out.ReadString('\n')
+	require.Nil(t, err)
+	assert.Equal(t, "ls\n", line)
+
+	_, err = c.CancelPlay(ctx, &client.CancelPlayRequest{ID: started.Play.ID})
+	require.Nil(t, err)
+
+	select {
+	case err := <-errs:
+		assert.Nil(t, err)
+	case <-time.After(time.Second * 5):
+		t.Fatal("shell did not finish")
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "play.json")
+
+	s := srepfake.New(nil)
+	_, token := s.NewUser("Bongo", "bongo@srep.io", "hunter2hunter2")
+
+	rec, err := New(path, &Options{Mode: ModeAuto})
+	require.Nil(t, err)
+	require.Equal(t, ModeRecord, rec.Mode())
+	script(t, client.NewClient(rec.Configure(&client.ClientOptions{
+		Url:    s.Host(),
+		Scheme: "http",
+		Token:  token,
+	})))
+	require.Nil(t, rec.Stop())
+	s.Close()
+
+	raw, err := os.ReadFile(path)
+	require.Nil(t, err)
+	assert.False(t, strings.Contains(string(raw), token))
+
+	replay, err := New(path, &Options{Mode: ModeAuto})
+	require.Nil(t, err)
+	require.Equal(t, ModeReplay, replay.Mode())
+	c := client.NewClient(replay.Configure(&client.ClientOptions{
+		Url:    s.Host(),
+		Scheme: "http",
+	}))
+	script(t, c)
+
+	_, err = c.GetPlays(context.Background(), &client.GetPlaysRequest{})
+	assert.ErrorIs(t, err, ErrNoInteraction)
+}
+
+func TestShellFramesAreMasked(t *testing.T) {
+	r := &redactor{}
+	text := func(p types.Payload) string {
+		ev, err := types.EncodeEvent(p)
+		require.Nil(t, err)
+		ev.EncodeBase64()
+		out := r.redact(ev)
+		require.Nil(t, out.Decode())
+		return out.Content
+	}
+
+	assert.Equal(t, "[sudo] password for bongo: ", text(&types.OutputPayload{Data: []byte("[sudo] password for bongo: ")}))
+	assert.Equal(t, redact.Marker, text(&types.InputPayload{Data: []byte("hun")}))
+	assert.Equal(t, redact.Marker+"\r", text(&types.InputPayload{Data: []byte("ter2\r")}))
+	assert.Equal(t, "ls\n", text(&types.InputPayload{Data: []byte("ls\n")}))
+	assert.Equal(t, "export API_KEY="+redact.Marker+"\n", text(&types.InputPayload{Data: []byte("export API_KEY=hunter2\n")}))
+	assert.Equal(t, "Authorization: Bearer "+redact.Marker+"\r\n", text(&types.OutputPayload{Data: []byte("Authorization: Bearer abcdefghijkl\r\n")}))
+}
+
+func TestReplayRequiresACassette(t *testing.T) {
+	_, err := New(filepath.Join(t.TempDir(), "missing.json"), &Options{Mode: ModeReplay})
+	assert.Error(t, err)
+}
+
+func pipe(t *testing.T) (*os.File, *os.File) {
+	r, w, err := os.Pipe()
+	require.Nil(t, err)
+	t.Cleanup(func() {
+		r.Close()
+		w.Close()
+	})
+	return r, w
+}
@@ -0,0 +1,222 @@
+package cassette
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/srepio/sdk/client"
+	"github.com/srepio/sdk/internal/redact"
+	"github.com/srepio/sdk/types"
+)
+
+var (
+	errSocketClosed = errors.New("socket closed")
+)
+
+func requestURI(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return u.RequestURI()
+}
+
+// How much of the current output line is kept to spot prompts
+const promptWindow = 256
+
+// Masks credentials in recorded frames. Shell input and output are masked a
+// frame at a time with the same patterns as asciicast recordings, and input
+// answering a secret prompt is masked up to the end of the line. Anything
+// else typed a keystroke at a time spans several frames so is not caught.
+type redactor struct {
+	terminals map[string]*terminalText
+}
+
+// What has been seen on one terminal of the socket
+type terminalText struct {
+	line   string
+	secret bool
+}
+
+func (r *redactor) redact(ev *types.SocketEvent) *types.SocketEvent {
+	out := *ev
+	switch ev.Type {
+	case types.ActivePlay:
+		out.Content = string(redact.JSON([]byte(ev.Content)))
+	case types.Input, types.Output:
+		encoded := out.Encoding == types.EncodingBase64
+		if err := out.Decode(); err != nil {
+			out.Content = redact.Marker
+			return &out
+		}
+		t := r.terminal(ev.Terminal)
+		if ev.Type == types.Input {
+			out.Content = t.input(out.Content)
+		} else {
+			out.Content = t.output(out.Content)
+		}
+		if encoded {
+			out.EncodeBase64()
+		}
+	}
+	return &out
+}
+
+func (r *redactor) terminal(name string) *terminalText {
+	if r.terminals == nil {
+		r.terminals = map[string]*terminalText{}
+	}
+	t, ok := r.terminals[name]
+	if !ok {
+		t = &terminalText{}
+		r.terminals[name] = t
+	}
+	return t
+}
+
+func (t *terminalText) output(data string) string {
+	if i := strings.LastIndexAny(data, "\r\n"); i >= 0 {
+		t.line = data[i+1:]
+	} else {
+		t.line += data
+	}
+	if len(t.line) > promptWindow {
+		t.line = t.line[len(t.line)-promptWindow:]
+	}
+	if redact.Prompted(t.line, redact.Prompts) {
+		t.secret = true
+	}
+	return redact.Text(data, redact.Secrets)
+}
+
+func (t *terminalText) input(data string) string {
+	if t.secret {
+		i := strings.IndexAny(data, "\r\n")
+		if i < 0 {
+			return redact.Marker
+		}
+		t.secret = false
+		t.line = ""
+		return redact.Marker + redact.Text(data[i:], redact.Secrets)
+	}
+	return redact.Text(data, redact.Secrets)
+}
+
+type recordingSocket struct {
+	next   client.Socket
+	redact *redactor
+	rec    *Socket
+	mu     *sync.Mutex
+	start  time.Time
+}
+
+func (s *recordingSocket) Read() (*types.SocketEvent, error) {
+	ev, err := s.next.Read()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		var ce *websocket.CloseError
+		if errors.As(err, &ce) && s.rec.Close == nil {
+			s.rec.Close = &CloseErr{Code: ce.Code, Text: ce.Text}
+		}
+		return nil, err
+	}
+	s.rec.Frames = append(s.rec.Frames, &Frame{Direction: Received, Event: s.redact.redact(ev), Time: time.Since(s.start)})
+	return ev, nil
+}
+
+func (s *recordingSocket) Write(msg *types.SocketEvent) error {
+	s.mu.Lock()
+	s.rec.Frames = append(s.rec.Frames, &Frame{Direction: Sent, Event: s.redact.redact(msg), Time: time.Since(s.start)})
+	s.mu.Unlock()
+
+	return s.next.Write(msg)
+}
+
+func (s *recordingSocket) Close() error {
+	return s.next.Close()
+}
+
+// Replays received frames in order. A received frame is only delivered once
+// the caller has written as many input events as preceded it in the
+// recording, so output never overtakes the input that produced it. Other
+// sent frames such as pings and resizes are timing dependent so are not
+// waited on.
+type replaySocket struct {
+	rec    *Socket
+	mu     *sync.Mutex
+	cond   *sync.Cond
+	pos    int
+	inputs int
+	closed bool
+}
+
+func newReplaySocket(rec *Socket) *replaySocket {
+	s := &replaySocket{
+		rec: rec,
+		mu:  &sync.Mutex{},
+	}
+	s.cond = sync.NewCond(s.mu)
+	return s
+}
+
+func (s *replaySocket) Read() (*types.SocketEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	needed := 0
+	for ; s.pos < len(s.rec.Frames); s.pos++ {
+		f := s.rec.Frames[s.pos]
+		if f.Direction == Sent {
+			if f.Event.Type == types.Input {
+				needed++
+			}
+			continue
+		}
+
+		for s.inputs < needed && !s.closed {
+			s.cond.Wait()
+		}
+		if s.closed {
+			return nil, errSocketClosed
+		}
+		s.inputs -= needed
+		s.pos++
+		ev := *f.Event
+		return &ev, nil
+	}
+
+	if s.rec.Close != nil {
+		return nil, &websocket.CloseError{Code: s.rec.Close.Code, Text: s.rec.Close.Text}
+	}
+	return nil, io.EOF
+}
+
+func (s *replaySocket) Write(msg *types.SocketEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errSocketClosed
+	}
+	if msg.Type == types.Input {
+		s.inputs++
+		s.cond.Broadcast()
+	}
+	return nil
+}
+
+func (s *replaySocket) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	s.cond.Broadcast()
+	return nil
+}
@@ -0,0 +1,218 @@
+// Package chaos injects faults into the HTTP calls and shell sockets made by
+// a client, so services built on the SDK can be tested against failure.
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/srepio/sdk/client"
+	"github.com/srepio/sdk/types"
+)
+
+var (
+	ErrReset = fmt.Errorf("injected fault: %w", syscall.ECONNRESET)
+)
+
+type Fault int
+
+const (
+	// Delay the request or frame
+	Latency Fault = iota
+	// Fail the request or socket as if the connection was reset
+	Reset
+	// Respond with a status code without reaching the server
+	Status
+	// Cut the response body short
+	Truncate
+	// Silently drop a socket frame
+	Drop
+	// Deliver a socket frame twice
+	Duplicate
+)
+
+func (f Fault) String() string {
+	switch f {
+	case Latency:
+		return "latency"
+	case Reset:
+		return "reset"
+	case Status:
+		return "status"
+	case Truncate:
+		return "truncate"
+	case Drop:
+		return "drop"
+	case Duplicate:
+		return "duplicate"
+	default:
+		return fmt.Sprintf("fault(%d)", int(f))
+	}
+}
+
+type Direction int
+
+const (
+	Both Direction = iota
+	Sent
+	Received
+)
+
+type Rule struct {
+	Fault Fault
+	// The chance between 0 and 1 that the fault is injected
+	Probability float64
+	// Only apply to requests/sockets whose URL path matches
+	Path *regexp.Regexp
+	// How long to delay for Latency faults
+	Latency time.Duration
+	// Extra random delay of up to Jitter for Latency faults
+	Jitter time.Duration
+	// The status code for Status faults, defaults to 500. A Retry-After header
+	// is set for 429s
+	Status int
+	// Only apply to socket frames travelling in this direction
+	Direction Direction
+	// Only apply to socket frames of these types, all types when empty
+	Types []types.MessgaeType
+}
+
+func (r Rule) matchesPath(path string) bool {
+	return r.Path == nil || r.Path.MatchString(path)
+}
+
+// Rules scoped to socket frames by type or direction never apply to HTTP
+// requests or socket dials
+func (r Rule) matchesRequest(path string) bool {
+	return r.matchesPath(path) && r.Direction == Both && len(r.Types) == 0
+}
+
+func (r Rule) matchesEvent(dir Direction, ev *types.SocketEvent) bool {
+	if r.Direction != Both && r.Direction != dir {
+		return false
+	}
+	return len(r.Types) == 0 || slices.Contains(r.Types, ev.Type)
+}
+
+type Injector struct {
+	rules []Rule
+	mu    *sync.Mutex
+	rand  *rand.Rand
+	stats map[Fault]int
+}
+
+// Create an injector, using the same seed gives the same sequence of faults
+func New(seed int64, rules ...Rule) *Injector {
+	return &Injector{
+		rules: rules,
+		mu:    &sync.Mutex{},
+		rand:  rand.New(rand.NewSource(seed)),
+		stats: map[Fault]int{},
+	}
+}
+
+// Wrap the transport and socket dialer in the client options
+func (i *Injector) Configure(opts *client.ClientOptions) *client.ClientOptions {
+	opts.Transport = i.Transport(opts.Transport)
+	opts.SocketDialer = i.SocketDialer(opts.SocketDialer)
+	return opts
+}
+
+// How many times each fault has been injected
+func (i *Injector) Stats() map[Fault]int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	out := map[Fault]int{}
+	for f, n := range i.stats {
+		out[f] = n
+	}
+	return out
+}
+
+func (i *Injector) roll(r Rule) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if r.Probability <= 0 || i.rand.Float64() >= r.Probability {
+		return false
+	}
+	i.stats[r.Fault]++
+	return true
+}
+
+func (i *Injector) jitter(r Rule) time.Duration {
+	if r.Jitter <= 0 {
+		return r.Latency
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return r.Latency + time.Duration(i.rand.Int63n(int64(r.Jitter)))
+}
+
+// Runs the rules that apply, sleeping for latency faults and returning the
+// first other fault that fires
+func (i *Injector) pick(ctx context.Context, applies func(Rule) bool, allowed ...Fault) (*Rule, error) {
+	for _, r := range i.rules {
+		if !applies(r) || (r.Fault != Latency && !slices.Contains(allowed, r.Fault)) {
+			continue
+		}
+		if !i.roll(r) {
+			continue
+		}
+		if r.Fault != Latency {
+			return &r, nil
+		}
+		if err := sleep(ctx, i.jitter(r)); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func statusResponse(req *http.Request, code int) *http.Response {
+	if code == 0 {
+		code = http.StatusInternalServerError
+	}
+	body := `{"message":"injected fault"}`
+	resp := &http.Response{
+		Status:     fmt.Sprintf("%d %s", code, http.StatusText(code)),
+		StatusCode: code,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header: http.Header{
+			"Content-Type": []string{"application/json"},
+		},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+	if code == http.StatusTooManyRequests {
+		resp.Header.Set("Retry-After", "1")
+	}
+	return resp
+}
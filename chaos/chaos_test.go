@@ -0,0 +1,192 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/srepio/sdk/client"
+	"github.com/srepio/sdk/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func prepare(t *testing.T, inj *Injector) (*client.Client, *int) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"plays":[{"id":"6aac65e9-17d2-4a34-8503-490138aa3ed5"}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return client.NewClient(inj.Configure(&client.ClientOptions{
+		Url:    strings.TrimPrefix(server.URL, "http://"),
+		Scheme: "http",
+	})), &hits
+}
+
+func TestHTTPFaults(t *testing.T) {
+	type testCase struct {
+		name  string
+		rule  Rule
+		check func(t *testing.T, err error, hits int)
+	}
+
+	cases := []testCase{
+		{
+			name: "status",
+			rule: Rule{Fault: Status, Status: http.StatusTooManyRequests, Probability: 1},
+			check: func(t *testing.T, err error, hits int) {
+				assert.ErrorContains(t, err, "429")
+				assert.Equal(t, 0, hits)
+			},
+		},
+		{
+			name: "reset",
+			rule: Rule{Fault: Reset, Probability: 1},
+			check: func(t *testing.T, err error, hits int) {
+				assert.ErrorIs(t, err, syscall.ECONNRESET)
+				assert.Equal(t, 0, hits)
+			},
+		},
+		{
+			name: "truncate",
+			rule: Rule{Fault: Truncate, Probability: 1},
+			check: func(t *testing.T, err error, hits int) {
+				assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+				assert.Equal(t, 1, hits)
+			},
+		},
+		{
+			name: "other_path",
+			rule: Rule{Fault: Reset, Probability: 1, Path: regexp.MustCompile("^/auth")},
+			check: func(t *testing.T, err error, hits int) {
+				assert.Nil(t, err)
+				assert.Equal(t, 1, hits)
+			},
+		},
+		{
+			name: "never",
+			rule: Rule{Fault: Reset, Probability: 0},
+			check: func(t *testing.T, err error, hits int) {
+				assert.Nil(t, err)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, hits := prepare(t, New(1, tc.rule))
+			_, err := c.GetPlays(context.Background(), &client.GetPlaysRequest{})
+			tc.check(t, err, *hits)
+		})
+	}
+}
+
+func TestLatency(t *testing.T) {
+	inj := New(1, Rule{Fault: Latency, Latency: time.Millisecond * 20, Probability: 1})
+	c, _ := prepare(t, inj)
+
+	start := time.Now()
+	_, err := c.GetPlays(context.Background(), &client.GetPlaysRequest{})
+	assert.Nil(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), time.Millisecond*20)
+	assert.Equal(t, 1, inj.Stats()[Latency])
+}
+
+func TestFrameRulesSkipRequests(t *testing.T) {
+	inj := New(1,
+		Rule{Fault: Latency, Latency: time.Second, Probability: 1, Types: []types.MessgaeType{types.Output}},
+		Rule{Fault: Latency, Latency: time.Second, Probability: 1, Direction: Received},
+	)
+	c, _ := prepare(t, inj)
+
+	start := time.Now()
+	_, err := c.GetPlays(context.Background(), &client.GetPlaysRequest{})
+	assert.Nil(t, err)
+	assert.Less(t, time.Since(start), time.Millisecond*500)
+	assert.Zero(t, inj.Stats()[Latency])
+}
+
+func TestSeededInjectorsAreDeterministic(t *testing.T) {
+	run := func() []bool {
+		c, _ := prepare(t, New(42, Rule{Fault: Status, Status: 503, Probability: 0.5}))
+		out := []bool{}
+		for i := 0; i < 20; i++ {
+			_, err := c.GetPlays(context.Background(), &client.GetPlaysRequest{})
+			out = append(out, err != nil)
+		}
+		return out
+	}
+
+	assert.Equal(t, run(), run())
+}
+
+type stubSocket struct {
+	in  []*types.SocketEvent
+	out []*types.SocketEvent
+}
+
+func (s *stubSocket) Read() (*types.SocketEvent, error) {
+	if len(s.in) == 0 {
+		return nil, io.EOF
+	}
+	ev := s.in[0]
+	s.in = s.in[1:]
+	return ev, nil
+}
+
+func (s *stubSocket) Write(msg *types.SocketEvent) error {
+	s.out = append(s.out, msg)
+	return nil
+}
+
+func (s *stubSocket) Close() error {
+	return nil
+}
+
+func TestSocketFaults(t *testing.T) {
+	stub := &stubSocket{
+		in: []*types.SocketEvent{
+			{Type: types.Ping},
+			{Type: types.Output, Content: "a"},
+			{Type: types.Output, Content: "b"},
+		},
+	}
+	inj := New(1,
+		Rule{Fault: Drop, Probability: 1, Direction: Received, Types: []types.MessgaeType{types.Ping}},
+		Rule{Fault: Duplicate, Probability: 1, Direction: Sent},
+	)
+	sock := inj.WrapSocket(stub, "/plays/id/shell")
+
+	read := []string{}
+	for {
+		ev, err := sock.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		assert.Nil(t, err)
+		read = append(read, ev.Content)
+	}
+	assert.Equal(t, []string{"a", "b"}, read)
+
+	assert.Nil(t, sock.Write(&types.SocketEvent{Type: types.Input, Content: "ls"}))
+	assert.Len(t, stub.out, 2)
+	assert.Equal(t, 1, inj.Stats()[Drop])
+	assert.Equal(t, 1, inj.Stats()[Duplicate])
+}
+
+func TestSocketReset(t *testing.T) {
+	inj := New(1, Rule{Fault: Reset, Probability: 1, Types: []types.MessgaeType{types.Output}})
+	sock := inj.WrapSocket(&stubSocket{in: []*types.SocketEvent{{Type: types.Output}}}, "")
+
+	_, err := sock.Read()
+	assert.ErrorIs(t, err, ErrReset)
+}
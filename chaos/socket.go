@@ -0,0 +1,140 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/srepio/sdk/client"
+	"github.com/srepio/sdk/types"
+)
+
+type dialer struct {
+	next client.SocketDialer
+	inj  *Injector
+}
+
+// Wrap a socket dialer with the injector's faults, nil wraps a websocket dialer
+func (i *Injector) SocketDialer(next client.SocketDialer) client.SocketDialer {
+	if next == nil {
+		next = client.NewWebsocketDialer(nil)
+	}
+	return &dialer{next: next, inj: i}
+}
+
+func (d *dialer) DialSocket(ctx context.Context, raw string, header http.Header) (client.Socket, *http.Response, error) {
+	path := raw
+	if u, err := url.Parse(raw); err == nil {
+		path = u.Path
+	}
+
+	rule, err := d.inj.pick(ctx, func(r Rule) bool { return r.matchesRequest(path) }, Reset, Status)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rule != nil {
+		if rule.Fault == Reset {
+			return nil, nil, ErrReset
+		}
+		return nil, statusResponse(nil, rule.Status), errors.New("websocket: bad handshake")
+	}
+
+	sock, resp, err := d.next.DialSocket(ctx, raw, header)
+	if err != nil {
+		return sock, resp, err
+	}
+	return d.inj.WrapSocket(sock, path), resp, nil
+}
+
+type socket struct {
+	next client.Socket
+	inj  *Injector
+	path string
+
+	mu      *sync.Mutex
+	pending []*types.SocketEvent
+}
+
+// Wrap a socket with the injector's frame faults, path is matched against
+// rules with a Path set
+func (i *Injector) WrapSocket(s client.Socket, path string) client.Socket {
+	return &socket{
+		next: s,
+		inj:  i,
+		path: path,
+		mu:   &sync.Mutex{},
+	}
+}
+
+func (s *socket) applies(dir Direction, ev *types.SocketEvent) func(Rule) bool {
+	return func(r Rule) bool {
+		return r.matchesPath(s.path) && r.matchesEvent(dir, ev)
+	}
+}
+
+func (s *socket) Read() (*types.SocketEvent, error) {
+	s.mu.Lock()
+	if len(s.pending) > 0 {
+		ev := s.pending[0]
+		s.pending = s.pending[1:]
+		s.mu.Unlock()
+		return ev, nil
+	}
+	s.mu.Unlock()
+
+	for {
+		ev, err := s.next.Read()
+		if err != nil {
+			return nil, err
+		}
+
+		rule, err := s.inj.pick(context.Background(), s.applies(Received, ev), Reset, Drop, Duplicate)
+		if err != nil {
+			return nil, err
+		}
+		if rule == nil {
+			return ev, nil
+		}
+
+		switch rule.Fault {
+		case Reset:
+			s.next.Close()
+			return nil, ErrReset
+		case Duplicate:
+			dup := *ev
+			s.mu.Lock()
+			s.pending = append(s.pending, &dup)
+			s.mu.Unlock()
+			return ev, nil
+		}
+		// Dropped, read the next frame instead
+	}
+}
+
+func (s *socket) Write(msg *types.SocketEvent) error {
+	rule, err := s.inj.pick(context.Background(), s.applies(Sent, msg), Reset, Drop, Duplicate)
+	if err != nil {
+		return err
+	}
+	if rule == nil {
+		return s.next.Write(msg)
+	}
+
+	switch rule.Fault {
+	case Reset:
+		s.next.Close()
+		return ErrReset
+	case Duplicate:
+		if err := s.next.Write(msg); err != nil {
+			return err
+		}
+		return s.next.Write(msg)
+	}
+	return nil
+}
+
+func (s *socket) Close() error {
+	return s.next.Close()
+}
@@ -0,0 +1,81 @@
+package chaos
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+type transport struct {
+	next http.RoundTripper
+	inj  *Injector
+}
+
+// Wrap a transport with the injector's HTTP faults, nil wraps http.DefaultTransport
+func (i *Injector) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{next: next, inj: i}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	applies := func(r Rule) bool {
+		return r.matchesRequest(req.URL.Path)
+	}
+
+	rule, err := t.inj.pick(req.Context(), applies, Reset, Status)
+	if err != nil {
+		return nil, err
+	}
+	if rule != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		if rule.Fault == Reset {
+			return nil, ErrReset
+		}
+		return statusResponse(req, rule.Status), nil
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	noLatency := func(r Rule) bool {
+		return r.Fault != Latency && applies(r)
+	}
+	rule, err = t.inj.pick(req.Context(), noLatency, Truncate)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if rule != nil {
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = &truncated{r: bytes.NewReader(body[:len(body)/2])}
+	}
+
+	return resp, nil
+}
+
+// Returns an unexpected EOF once the remaining bytes have been read
+type truncated struct {
+	r *bytes.Reader
+}
+
+func (t *truncated) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if err == io.EOF {
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (t *truncated) Close() error {
+	return nil
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+// The full set of operations supported by the SDK, satisfied by *Client
+type API interface {
+	Plays
+	Users
+	Scenarios
+	Shell
+	Files
+	Ports
+	Terminals
+	Spectators
+}
+
+type Plays interface {
+	StartPlay(ctx context.Context, req *StartPlayRequest) (*StartPlayResponse, error)
+	CheckPlay(ctx context.Context, req *CheckPlayRequest) (*CheckPlayResponse, error)
+	CancelPlay(ctx context.Context, req *CancelPlayRequest) (*CancelPlayResponse, error)
+	GetPlays(ctx context.Context, req *GetPlaysRequest) (*GetPlaysResponse, error)
+	GetActivePlay(ctx context.Context, req *GetActivePlayRequest) (*GetActivePlayResponse, error)
+	GetPlay(ctx context.Context, req *GetPlayRequest) (*GetPlayResponse, error)
+	Exec(ctx context.Context, req *ExecRequest) (*ExecResponse, error)
+}
+
+type Users interface {
+	CreateUser(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error)
+	Login(ctx context.Context, req *LoginRequest) (*LoginResponse, error)
+	VerifyMFA(ctx context.Context, req *VerifyMFARequest) (*LoginResponse, error)
+	Me(ctx context.Context, req *MeRequest) (*MeResponse, error)
+	GetApiTokens(ctx context.Context, req *GetApiTokensRequest) (*GetApiTokensResponse, error)
+	CreateApiToken(ctx context.Context, req *CreateApiTokenRequest) (*CreateApiTokenResponse, error)
+	DeleteApiToken(ctx context.Context, req *DeleteApiTokenRequest) (*DeleteApiTokenResponse, error)
+	ConfirmPassword(ctx context.Context, req *ConfirmPasswordRequest) (*ConfirmPasswordResponse, error)
+	UpdatePassword(ctx context.Context, req *UpdatePasswordRequest) (*UpdatePasswordResponse, error)
+	ConfigureMFA(ctx context.Context, req *ConfigureMFARequest) (*ConfigureMFAResponse, error)
+	RemoveMFA(ctx context.Context, req *RemoveMFARequest) (*RemoveMFAResponse, error)
+	Logout(ctx context.Context, req *LogoutRequest) (*LogoutResponse, error)
+	DeleteAccount(ctx context.Context, req *DeleteAccountRequest) (*DeleteAccountResponse, error)
+}
+
+type Scenarios interface {
+	GetScenarios(ctx context.Context, req *GetScenariosRequest) (*GetScenariosResponse, error)
+	FindScenario(ctx context.Context, req *FindScenarioRequest) (*FindScenarioResponse, error)
+}
+
+type Shell interface {
+	GetShell(ctx context.Context, req *GetShellRequest, stdin io.Reader, stdout io.Writer, opts *ShellOptions) error
+	GetTerminalShell(ctx context.Context, req *GetShellRequest, stdin *os.File, stdout *os.File) error
+	OpenShell(ctx context.Context, req *GetShellRequest, opts *ShellOptions) (*ShellSession, error)
+}
+
+type Files interface {
+	UploadFile(ctx context.Context, req *UploadFileRequest) (*UploadFileResponse, error)
+	DownloadFile(ctx context.Context, req *DownloadFileRequest, w io.Writer) (*DownloadFileResponse, error)
+	UploadTar(ctx context.Context, req *UploadTarRequest) (*UploadTarResponse, error)
+	DownloadTar(ctx context.Context, req *DownloadTarRequest, w io.Writer) (*DownloadTarResponse, error)
+	UploadDir(ctx context.Context, req *UploadDirRequest) (*UploadTarResponse, error)
+	DownloadDir(ctx context.Context, req *DownloadDirRequest) (*DownloadTarResponse, error)
+}
+
+type Ports interface {
+	Forward(ctx context.Context, req *ForwardRequest) (*Forwarder, error)
+}
+
+type Terminals interface {
+	GetTerminals(ctx context.Context, req *GetTerminalsRequest) (*GetTerminalsResponse, error)
+	CloseTerminal(ctx context.Context, req *CloseTerminalRequest) (*CloseTerminalResponse, error)
+	OpenTerminalMux(ctx context.Context, req *GetShellRequest) (*TerminalMux, error)
+}
+
+type Spectators interface {
+	GetSpectators(ctx context.Context, req *GetSpectatorsRequest) (*GetSpectatorsResponse, error)
+	AddSpectator(ctx context.Context, req *AddSpectatorRequest) (*AddSpectatorResponse, error)
+	RemoveSpectator(ctx context.Context, req *RemoveSpectatorRequest) (*RemoveSpectatorResponse, error)
+	WatchShell(ctx context.Context, req *WatchShellRequest, opts *ShellOptions) (*ShellSession, error)
+}
+
+var _ API = (*Client)(nil)
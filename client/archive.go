@@ -0,0 +1,308 @@
+package client
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type UploadTarRequest struct {
+	ID string `json:"id" param:"id"`
+	// The directory in the play the archive is extracted into
+	Path string `json:"path"`
+	// A tar stream, entry names are relative to Path
+	Body     io.Reader    `json:"-"`
+	Progress ProgressFunc `json:"-"`
+}
+
+func (r UploadTarRequest) Validate() error {
+	if r.Body == nil {
+		return validation.Errors{"body": validation.ErrNil}
+	}
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.ID, validation.Required, validation.Match(regexp.MustCompile(uuidRegex))),
+		validation.Field(&r.Path, validation.Required, validation.Match(absolutePath)),
+	)
+}
+
+type UploadTarResponse struct {
+	Path  string `json:"path"`
+	Files int    `json:"files"`
+	// Of the archive rather than the files in it
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Extract a tar stream into a directory in a play
+func (c *Client) UploadTar(ctx context.Context, req *UploadTarRequest) (*UploadTarResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	sum := sha256.New()
+	hreq := c.stream(http.MethodPut, fmt.Sprintf("/plays/%s/archive", req.ID), url.Values{"path": {req.Path}}, counted(io.TeeReader(req.Body, sum), -1, req.Progress))
+	hreq.Header.Set("Content-Type", "application/x-tar")
+
+	resp, err := do[UploadTarResponse](ctx, c.hc, hreq)
+	if err != nil {
+		return nil, err
+	}
+	if err := verify(sum, resp.SHA256); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+type DownloadTarRequest struct {
+	ID       string       `json:"id" param:"id"`
+	Path     string       `json:"path"`
+	Progress ProgressFunc `json:"-"`
+}
+
+func (r DownloadTarRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.ID, validation.Required, validation.Match(regexp.MustCompile(uuidRegex))),
+		validation.Field(&r.Path, validation.Required, validation.Match(absolutePath)),
+	)
+}
+
+type DownloadTarResponse struct {
+	// Of the archive rather than the files in it
+	Size   int64
+	SHA256 string
+}
+
+// Stream a directory in a play to w as a tar archive, entry names are
+// relative to the directory
+func (c *Client) DownloadTar(ctx context.Context, req *DownloadTarRequest, w io.Writer) (*DownloadTarResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	hreq := c.stream(http.MethodGet, fmt.Sprintf("/plays/%s/archive", req.ID), url.Values{"path": {req.Path}}, nil)
+	_, sum, n, err := c.download(ctx, hreq, w, req.Progress)
+	if err != nil {
+		return nil, err
+	}
+	return &DownloadTarResponse{Size: n, SHA256: sum}, nil
+}
+
+type UploadDirRequest struct {
+	ID string `json:"id" param:"id"`
+	// The directory in the play the files are written to
+	Path string `json:"path"`
+	// The local directory to upload
+	Dir      string       `json:"-"`
+	Progress ProgressFunc `json:"-"`
+}
+
+func (r UploadDirRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.ID, validation.Required, validation.Match(regexp.MustCompile(uuidRegex))),
+		validation.Field(&r.Path, validation.Required, validation.Match(absolutePath)),
+		validation.Field(&r.Dir, validation.Required),
+	)
+}
+
+// Upload a local directory to a play. Only regular files and directories are
+// sent.
+func (c *Client) UploadDir(ctx context.Context, req *UploadDirRequest) (*UploadTarResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeTar(pw, req.Dir))
+	}()
+	defer pr.Close()
+
+	return c.UploadTar(ctx, &UploadTarRequest{
+		ID:       req.ID,
+		Path:     req.Path,
+		Body:     pr,
+		Progress: req.Progress,
+	})
+}
+
+type DownloadDirRequest struct {
+	ID string `json:"id" param:"id"`
+	// The directory in the play to download
+	Path string `json:"path"`
+	// The local directory the files are written to, it is created if needed
+	Dir      string       `json:"-"`
+	Progress ProgressFunc `json:"-"`
+}
+
+func (r DownloadDirRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.ID, validation.Required, validation.Match(regexp.MustCompile(uuidRegex))),
+		validation.Field(&r.Path, validation.Required, validation.Match(absolutePath)),
+		validation.Field(&r.Dir, validation.Required),
+	)
+}
+
+// Download a directory from a play. Entries that would be written outside
+// of Dir are rejected. The archive is extracted next to Dir and only moved
+// into it once its checksum has been verified, so a failed download leaves
+// Dir untouched.
+func (c *Client) DownloadDir(ctx context.Context, req *DownloadDirRequest) (*DownloadTarResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Clean(req.Dir)
+	if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+		return nil, err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), "."+filepath.Base(dir)+"-download-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	pr, pw := io.Pipe()
+	extracted := make(chan error, 1)
+	go func() {
+		err := extractTar(pr, tmp)
+		// Drain anything after the archive so the checksum covers it all
+		io.Copy(io.Discard, pr)
+		pr.CloseWithError(err)
+		extracted <- err
+	}()
+
+	resp, err := c.DownloadTar(ctx, &DownloadTarRequest{
+		ID:       req.ID,
+		Path:     req.Path,
+		Progress: req.Progress,
+	}, pw)
+	pw.CloseWithError(err)
+	if xerr := <-extracted; xerr != nil && err == nil {
+		err = xerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := moveTree(tmp, dir); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Move the files under src into dst, merging with any directories already
+// there
+func moveTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+		return os.Rename(path, target)
+	})
+}
+
+func writeTar(w io.Writer, dir string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if d.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := filepath.FromSlash(hdr.Name)
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("archive entry %q is outside of the directory", hdr.Name)
+		}
+		path := filepath.Join(dir, name)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, hdr.FileInfo().Mode().Perm())
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/srepio/sdk/cassette"
+	"github.com/srepio/sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPlaysCassette(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "get_plays.json")
+
+	run := func(t *testing.T, mode cassette.Mode, extra func(*testing.T)) {
+		rec, err := cassette.New(path, &cassette.Options{Mode: mode})
+		require.Nil(t, err)
+
+		tc := client.APITestCase{
+			Url:       "/plays",
+			Code:      http.StatusOK,
+			Body:      `{"plays":[{"id":"6aac65e9-17d2-4a34-8503-490138aa3ed5"}]}`,
+			Extra:     extra,
+			Configure: rec.Configure,
+		}
+		s, c := tc.Prepare(t)
+		defer s.Close()
+
+		resp, err := c.GetPlays(context.Background(), &client.GetPlaysRequest{})
+		assert.Nil(t, err)
+		assert.Len(t, resp.Plays, 1)
+		require.Nil(t, rec.Stop())
+	}
+
+	// The first run records against the test server, the second must be
+	// served from the cassette alone
+	run(t, cassette.ModeRecord, nil)
+	run(t, cassette.ModeReplay, func(t *testing.T) {
+		t.Error("replayed request reached the server")
+	})
+}
@@ -14,6 +14,8 @@ import (
 
 type Client struct {
 	hc      *http.Client
+	dialer  SocketDialer
+	dump    *dumper
 	Options *ClientOptions
 }
 
@@ -22,6 +24,14 @@ type ClientOptions struct {
 	Token   string
 	Url     string
 	Scheme  string
+	// Dump redacted request/response wire data, also enabled with SREP_DEBUG=http
+	Debug bool
+	// Where debug output is written, defaults to stderr
+	DebugOutput io.Writer
+	// The transport used for REST calls, defaults to http.DefaultTransport
+	Transport http.RoundTripper
+	// Opens the websockets used for shells, defaults to a websocket dialer
+	SocketDialer SocketDialer
 }
 
 func NewClient(opts *ClientOptions) *Client {
@@ -32,12 +42,30 @@ func NewClient(opts *ClientOptions) *Client {
 		opts.Scheme = "https"
 	}
 
-	return &Client{
+	c := &Client{
 		Options: opts,
 		hc: &http.Client{
-			Timeout: opts.Timeout,
+			Timeout:   opts.Timeout,
+			Transport: opts.Transport,
 		},
+		dialer: opts.SocketDialer,
 	}
+	if c.dialer == nil {
+		c.dialer = NewWebsocketDialer(nil)
+	}
+	if debugEnabled(opts) {
+		c.dump = newDumper(opts.DebugOutput)
+		next := opts.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		c.hc.Transport = &debugTransport{
+			next: next,
+			dump: c.dump,
+		}
+	}
+
+	return c
 }
 
 func (c *Client) get(path string, params map[string]string) *http.Request {
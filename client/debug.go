@@ -0,0 +1,144 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/srepio/sdk/internal/redact"
+)
+
+const (
+	// Set SREP_DEBUG=http to dump request/response wire data
+	debugEnv  = "SREP_DEBUG"
+	debugHTTP = "http"
+)
+
+func debugEnabled(opts *ClientOptions) bool {
+	if opts.Debug {
+		return true
+	}
+	for _, mode := range strings.Split(os.Getenv(debugEnv), ",") {
+		if strings.TrimSpace(mode) == debugHTTP {
+			return true
+		}
+	}
+	return false
+}
+
+type dumper struct {
+	out io.Writer
+	mu  *sync.Mutex
+}
+
+func newDumper(out io.Writer) *dumper {
+	if out == nil {
+		out = os.Stderr
+	}
+	return &dumper{
+		out: out,
+		mu:  &sync.Mutex{},
+	}
+}
+
+func (d *dumper) request(method, url string, header http.Header, body []byte) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "> %s %s\n", method, url)
+	redact.Header(header).Write(buf)
+	writeBody(buf, header, body)
+	d.write(buf.Bytes())
+}
+
+func (d *dumper) response(resp *http.Response, body []byte) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "< %s %s\n", resp.Proto, resp.Status)
+	redact.Header(resp.Header).Write(buf)
+	writeBody(buf, resp.Header, body)
+	d.write(buf.Bytes())
+}
+
+func (d *dumper) error(method, url string, err error) {
+	d.write([]byte(fmt.Sprintf("< %s %s: %v\n\n", method, url, err)))
+}
+
+func (d *dumper) write(b []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.out.Write(b)
+}
+
+func writeBody(buf *bytes.Buffer, header http.Header, body []byte) {
+	buf.WriteString("\n")
+	if !isJSON(header) {
+		if header.Get("Content-Type") != "" {
+			buf.WriteString("[body omitted]\n\n")
+		}
+		return
+	}
+	if len(body) == 0 {
+		return
+	}
+	buf.Write(redact.JSON(body))
+	buf.WriteString("\n\n")
+}
+
+func isJSON(header http.Header) bool {
+	ct := header.Get("Content-Type")
+	if ct == "" {
+		return false
+	}
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	return mt == "application/json"
+}
+
+type debugTransport struct {
+	next http.RoundTripper
+	dump *dumper
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := drain(&req.Body, req.Header)
+	if err != nil {
+		return nil, err
+	}
+	t.dump.request(req.Method, req.URL.String(), req.Header, body)
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		t.dump.error(req.Method, req.URL.String(), err)
+		return nil, err
+	}
+
+	body, err = drain(&resp.Body, resp.Header)
+	if err != nil {
+		t.dump.error(req.Method, req.URL.String(), err)
+		return nil, err
+	}
+	t.dump.response(resp, body)
+
+	return resp, nil
+}
+
+// Reads a JSON body and replaces it with an in-memory copy, other bodies
+// are left alone so streams are not buffered. The original body is closed
+// even when reading it fails, so the connection is released.
+func drain(body *io.ReadCloser, header http.Header) ([]byte, error) {
+	if *body == nil || *body == http.NoBody || !isJSON(header) {
+		return nil, nil
+	}
+	b, err := io.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, err
+	}
+	*body = io.NopCloser(bytes.NewReader(b))
+	return b, nil
+}
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/srepio/sdk/internal/redact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugDumpsRedactedWireData(t *testing.T) {
+	tc := apiTestCase{
+		Url:         "/auth/login",
+		Code:        http.StatusOK,
+		Body:        `{"token":"supersecret","user":{"name":"bongo"}}`,
+		ContentType: "application/json",
+		Headers: map[string]string{
+			"Authorization": "Bearer apitoken",
+		},
+	}
+	s, c := tc.Prepare(t)
+	defer s.Close()
+
+	out := &bytes.Buffer{}
+	c = NewClient(&ClientOptions{
+		Url:         c.Options.Url,
+		Scheme:      c.Options.Scheme,
+		Token:       "apitoken",
+		Debug:       true,
+		DebugOutput: out,
+	})
+
+	_, err := c.Login(context.Background(), &LoginRequest{
+		Email:    "bongo@srep.io",
+		Password: "hunter2hunter2",
+	})
+	assert.Nil(t, err)
+
+	dump := out.String()
+	assert.Contains(t, dump, "> POST http://")
+	assert.Contains(t, dump, "/auth/login")
+	assert.Contains(t, dump, `"email":"bongo@srep.io"`)
+	assert.Contains(t, dump, "< HTTP/1.1 200 OK")
+	assert.NotContains(t, dump, "hunter2hunter2")
+	assert.NotContains(t, dump, "apitoken")
+	assert.NotContains(t, dump, "supersecret")
+	assert.Contains(t, dump, `"token":"[REDACTED]"`)
+	assert.Contains(t, dump, redact.Marker)
+}
+
+func TestDebugEnabledFromEnv(t *testing.T) {
+	t.Setenv(debugEnv, "http")
+	assert.True(t, debugEnabled(&ClientOptions{}))
+
+	t.Setenv(debugEnv, "")
+	assert.False(t, debugEnabled(&ClientOptions{}))
+	assert.True(t, debugEnabled(&ClientOptions{Debug: true}))
+}
+
+// A body that fails part way through and remembers being closed
+type failingBody struct {
+	closed bool
+}
+
+func (b *failingBody) Read(p []byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func (b *failingBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+type stubTransport struct {
+	resp *http.Response
+}
+
+func (t *stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.resp, nil
+}
+
+func TestDebugClosesBodiesThatFailToRead(t *testing.T) {
+	body := &failingBody{}
+	tr := &debugTransport{
+		next: &stubTransport{resp: &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       body,
+		}},
+		dump: newDumper(&bytes.Buffer{}),
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "http://srep.io/plays", nil)
+	require.Nil(t, err)
+	_, err = tr.RoundTrip(req)
+	assert.Error(t, err)
+	assert.True(t, body.closed)
+}
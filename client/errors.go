@@ -3,5 +3,16 @@ package client
 import "errors"
 
 var (
-	ErrTooEarly = errors.New("too early")
+	ErrTooEarly       = errors.New("too early")
+	ErrShellClosed    = errors.New("shell closed")
+	ErrDeadConnection = errors.New("connection stopped responding")
+	ErrPlayFinished   = errors.New("play finished")
+	// The content of a transfer didn't match its checksum
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// The terminal already has a session on the multiplexed socket
+	ErrTerminalOpen = errors.New("terminal already open")
+	// Spectators can watch a shell but not type into or resize it
+	ErrReadOnly = errors.New("shell is read-only")
+	// Shell output was not read fast enough and its backlog filled up
+	ErrOutputOverflow = errors.New("shell output not read in time")
 )
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type ExecRequest struct {
+	ID string `json:"id" param:"id"`
+	// Run by the play's shell so pipes and redirects work
+	Command string `json:"command"`
+	// Written to the command's standard input
+	Stdin []byte `json:"stdin,omitempty"`
+	// Seconds the command can run for before it is killed, the server's
+	// limit applies when zero
+	Timeout uint `json:"timeout,omitempty"`
+}
+
+func (r ExecRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.ID, validation.Required, validation.Match(regexp.MustCompile(uuidRegex))),
+		validation.Field(&r.Command, validation.Required),
+	)
+}
+
+type ExecResponse struct {
+	Stdout   []byte `json:"stdout"`
+	Stderr   []byte `json:"stderr"`
+	ExitCode int    `json:"exit_code"`
+	// Whether the command was killed for running past its timeout
+	TimedOut bool `json:"timed_out,omitempty"`
+}
+
+// An *ExitError when the command failed, for callers that only care whether
+// it worked
+func (r *ExecResponse) Err() error {
+	if r.ExitCode == 0 && !r.TimedOut {
+		return nil
+	}
+	return &ExitError{Code: r.ExitCode, Stderr: r.Stderr, TimedOut: r.TimedOut}
+}
+
+type ExitError struct {
+	Code     int
+	Stderr   []byte
+	TimedOut bool
+}
+
+func (e *ExitError) Error() string {
+	if e.TimedOut {
+		return "command timed out"
+	}
+	return fmt.Sprintf("command exited with status %d", e.Code)
+}
+
+// Run a command in a play without a terminal and wait for it to finish. A
+// non-zero exit status is not an error, see ExecResponse.Err.
+func (c *Client) Exec(ctx context.Context, req *ExecRequest) (*ExecResponse, error) {
+	hreq, err := c.buildRequest(http.MethodPost, fmt.Sprintf("/plays/%s/exec", req.ID), req, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return do[ExecResponse](ctx, c.hc, hreq)
+}
@@ -0,0 +1,5 @@
+package client
+
+// Lets tests in the client_test package, which can import packages that
+// depend on this one, use the API test helper
+type APITestCase = apiTestCase
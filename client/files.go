@@ -0,0 +1,219 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// Carries the SHA-256 of a download, as a header when it is known up front
+// and as a trailer when the content is streamed
+const checksumHeader = "X-Checksum-Sha256"
+
+const fileModeHeader = "X-File-Mode"
+
+var absolutePath = regexp.MustCompile(`^/`)
+
+// Reports how many bytes of a transfer are done, total is -1 when the size
+// isn't known
+type ProgressFunc func(done, total int64)
+
+type UploadFileRequest struct {
+	ID string `json:"id" param:"id"`
+	// Where the file is written in the play, parent directories are created
+	Path string `json:"path"`
+	// Defaults to 0644
+	Mode os.FileMode `json:"mode,omitempty"`
+	Body io.Reader   `json:"-"`
+	// The size of Body when it is known, used to report progress
+	Size     int64        `json:"-"`
+	Progress ProgressFunc `json:"-"`
+}
+
+func (r UploadFileRequest) Validate() error {
+	if r.Body == nil {
+		return validation.Errors{"body": validation.ErrNil}
+	}
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.ID, validation.Required, validation.Match(regexp.MustCompile(uuidRegex))),
+		validation.Field(&r.Path, validation.Required, validation.Match(absolutePath)),
+	)
+}
+
+type UploadFileResponse struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Write a file to a play. The checksum the play reports is compared with
+// what was sent and ErrChecksumMismatch returned when they differ.
+func (c *Client) UploadFile(ctx context.Context, req *UploadFileRequest) (*UploadFileResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	params := url.Values{"path": {req.Path}}
+	if req.Mode != 0 {
+		params.Set("mode", strconv.FormatUint(uint64(req.Mode.Perm()), 8))
+	}
+	sum := sha256.New()
+	hreq := c.stream(http.MethodPut, fmt.Sprintf("/plays/%s/files", req.ID), params, counted(io.TeeReader(req.Body, sum), req.Size, req.Progress))
+	if req.Size > 0 {
+		hreq.ContentLength = req.Size
+	}
+
+	resp, err := do[UploadFileResponse](ctx, c.hc, hreq)
+	if err != nil {
+		return nil, err
+	}
+	if err := verify(sum, resp.SHA256); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+type DownloadFileRequest struct {
+	ID       string       `json:"id" param:"id"`
+	Path     string       `json:"path"`
+	Progress ProgressFunc `json:"-"`
+}
+
+func (r DownloadFileRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.ID, validation.Required, validation.Match(regexp.MustCompile(uuidRegex))),
+		validation.Field(&r.Path, validation.Required, validation.Match(absolutePath)),
+	)
+}
+
+type DownloadFileResponse struct {
+	Path   string
+	Size   int64
+	Mode   os.FileMode
+	SHA256 string
+}
+
+// Copy a file out of a play. The content is checked against the checksum
+// the play sent, when it sent one, once it has all been written to w, so w
+// has already seen it when ErrChecksumMismatch is returned.
+func (c *Client) DownloadFile(ctx context.Context, req *DownloadFileRequest, w io.Writer) (*DownloadFileResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	hreq := c.stream(http.MethodGet, fmt.Sprintf("/plays/%s/files", req.ID), url.Values{"path": {req.Path}}, nil)
+	resp, sum, n, err := c.download(ctx, hreq, w, req.Progress)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &DownloadFileResponse{
+		Path:   req.Path,
+		Size:   n,
+		SHA256: sum,
+	}
+	if mode, err := strconv.ParseUint(resp.Header.Get(fileModeHeader), 8, 32); err == nil {
+		out.Mode = os.FileMode(mode).Perm()
+	}
+	return out, nil
+}
+
+// Build a request with a raw body, the response is still JSON
+func (c *Client) stream(method, path string, params url.Values, body io.Reader) *http.Request {
+	req := &http.Request{
+		Method: method,
+		URL: &url.URL{
+			Scheme:   c.Options.Scheme,
+			Host:     c.Options.Url,
+			Path:     path,
+			RawQuery: params.Encode(),
+		},
+		Header: http.Header{},
+	}
+	if body != nil {
+		req.Body = io.NopCloser(body)
+		req.Header.Add("Content-Type", "application/octet-stream")
+	}
+	req.Header.Add("Accept", "application/json")
+
+	return c.headers(req)
+}
+
+// Copy a response body to w, checking it against the checksum the server
+// sent
+func (c *Client) download(ctx context.Context, req *http.Request, w io.Writer, progress ProgressFunc) (*http.Response, string, int64, error) {
+	resp, err := c.hc.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, "", 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 299 {
+		return nil, "", 0, fmt.Errorf("status code: %d", resp.StatusCode)
+	}
+
+	sum := sha256.New()
+	n, err := io.Copy(io.MultiWriter(w, sum), counted(resp.Body, resp.ContentLength, progress))
+	if err != nil {
+		return nil, "", n, err
+	}
+
+	want := resp.Header.Get(checksumHeader)
+	if want == "" {
+		want = resp.Trailer.Get(checksumHeader)
+	}
+	if err := verify(sum, want); err != nil {
+		return nil, "", n, err
+	}
+	return resp, hex.EncodeToString(sum.Sum(nil)), n, nil
+}
+
+// Compare a checksum with the one the server sent, servers that send none
+// can't be checked so nothing is compared
+func verify(sum hash.Hash, want string) error {
+	if want == "" {
+		return nil
+	}
+	got := hex.EncodeToString(sum.Sum(nil))
+	if want != got {
+		return fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, want, got)
+	}
+	return nil
+}
+
+// Wrap a reader so it reports progress, size is -1 or zero when unknown
+func counted(r io.Reader, size int64, progress ProgressFunc) io.Reader {
+	if progress == nil {
+		return r
+	}
+	if size <= 0 {
+		size = -1
+	}
+	return &progressReader{r: r, total: size, progress: progress}
+}
+
+type progressReader struct {
+	r        io.Reader
+	done     int64
+	total    int64
+	progress ProgressFunc
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.done += int64(n)
+		p.progress(p.done, p.total)
+	}
+	return n, err
+}
@@ -0,0 +1,231 @@
+package client
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadAndDownloadFile(t *testing.T) {
+	tc := prepareShell(t, nil)
+	ctx := context.Background()
+	content := bytes.Repeat([]byte("replicas: 3\n"), 1000)
+
+	var sent int64
+	up, err := tc.client.UploadFile(ctx, &UploadFileRequest{
+		ID:   tc.playID,
+		Path: "/etc/app/config.yaml",
+		Mode: 0o600,
+		Body: bytes.NewReader(content),
+		Size: int64(len(content)),
+		Progress: func(done, total int64) {
+			assert.Equal(t, int64(len(content)), total)
+			sent = done
+		},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, int64(len(content)), sent)
+	assert.Equal(t, int64(len(content)), up.Size)
+
+	out := &bytes.Buffer{}
+	var received int64
+	down, err := tc.client.DownloadFile(ctx, &DownloadFileRequest{
+		ID:   tc.playID,
+		Path: "/etc/app/../app/config.yaml",
+		Progress: func(done, total int64) {
+			received = done
+		},
+	}, out)
+	require.Nil(t, err)
+	assert.Equal(t, content, out.Bytes())
+	assert.Equal(t, int64(len(content)), received)
+	assert.Equal(t, os.FileMode(0o600), down.Mode)
+	assert.Equal(t, up.SHA256, down.SHA256)
+
+	_, err = tc.client.DownloadFile(ctx, &DownloadFileRequest{ID: tc.playID, Path: "/var/log/missing.log"}, out)
+	assert.Error(t, err)
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			w.Write([]byte(`{"sha256": "bongo"}`))
+			return
+		}
+		w.Header().Set(checksumHeader, "bongo")
+		w.Write([]byte("corrupted"))
+	}))
+	defer server.Close()
+	c := NewClient(&ClientOptions{
+		Url:    strings.TrimPrefix(server.URL, "http://"),
+		Scheme: "http",
+	})
+
+	_, err := c.UploadFile(context.Background(), &UploadFileRequest{ID: uuid.NewString(), Path: "/tmp/a", Body: strings.NewReader("a")})
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	_, err = c.DownloadFile(context.Background(), &DownloadFileRequest{ID: uuid.NewString(), Path: "/tmp/a"}, &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+}
+
+func TestDownloadWithoutAChecksum(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("unchecked"))
+	}))
+	defer server.Close()
+	c := NewClient(&ClientOptions{
+		Url:    strings.TrimPrefix(server.URL, "http://"),
+		Scheme: "http",
+	})
+
+	out := &bytes.Buffer{}
+	_, err := c.DownloadFile(context.Background(), &DownloadFileRequest{ID: uuid.NewString(), Path: "/tmp/a"}, out)
+	assert.Nil(t, err)
+	assert.Equal(t, "unchecked", out.String())
+}
+
+func TestDownloadDirLeavesDirAloneOnChecksumMismatch(t *testing.T) {
+	archive := &bytes.Buffer{}
+	tw := tar.NewWriter(archive)
+	tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "nginx.conf", Mode: 0o644, Size: 7})
+	tw.Write([]byte("corrupt"))
+	tw.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(checksumHeader, "bongo")
+		w.Write(archive.Bytes())
+	}))
+	defer server.Close()
+	c := NewClient(&ClientOptions{
+		Url:    strings.TrimPrefix(server.URL, "http://"),
+		Scheme: "http",
+	})
+
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "nginx")
+	require.Nil(t, os.MkdirAll(dir, 0o755))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "nginx.conf"), []byte("worker_processes 4;\n"), 0o644))
+
+	_, err := c.DownloadDir(context.Background(), &DownloadDirRequest{ID: uuid.NewString(), Path: "/etc/nginx", Dir: dir})
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+
+	data, err := os.ReadFile(filepath.Join(dir, "nginx.conf"))
+	require.Nil(t, err)
+	assert.Equal(t, "worker_processes 4;\n", string(data))
+	entries, err := os.ReadDir(parent)
+	require.Nil(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestUploadAndDownloadDir(t *testing.T) {
+	tc := prepareShell(t, nil)
+	ctx := context.Background()
+
+	local := t.TempDir()
+	require.Nil(t, os.MkdirAll(filepath.Join(local, "conf.d"), 0o755))
+	require.Nil(t, os.WriteFile(filepath.Join(local, "nginx.conf"), []byte("worker_processes 4;\n"), 0o644))
+	require.Nil(t, os.WriteFile(filepath.Join(local, "conf.d", "site.conf"), []byte("listen 80;\n"), 0o600))
+
+	up, err := tc.client.UploadDir(ctx, &UploadDirRequest{ID: tc.playID, Path: "/etc/nginx", Dir: local})
+	require.Nil(t, err)
+	assert.Equal(t, 2, up.Files)
+
+	out := filepath.Join(t.TempDir(), "nginx")
+	var progress int64
+	down, err := tc.client.DownloadDir(ctx, &DownloadDirRequest{
+		ID:   tc.playID,
+		Path: "/etc/nginx",
+		Dir:  out,
+		Progress: func(done, total int64) {
+			progress = done
+		},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, down.Size, progress)
+
+	data, err := os.ReadFile(filepath.Join(out, "conf.d", "site.conf"))
+	require.Nil(t, err)
+	assert.Equal(t, "listen 80;\n", string(data))
+	info, err := os.Stat(filepath.Join(out, "conf.d", "site.conf"))
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	data, err = os.ReadFile(filepath.Join(out, "nginx.conf"))
+	require.Nil(t, err)
+	assert.Equal(t, "worker_processes 4;\n", string(data))
+
+	// Single files in the directory can be fetched too
+	file := &bytes.Buffer{}
+	_, err = tc.client.DownloadFile(ctx, &DownloadFileRequest{ID: tc.playID, Path: "/etc/nginx/conf.d/site.conf"}, file)
+	require.Nil(t, err)
+	assert.Equal(t, "listen 80;\n", file.String())
+}
+
+func TestDownloadDirRejectsEscapingEntries(t *testing.T) {
+	archive := &bytes.Buffer{}
+	tw := tar.NewWriter(archive)
+	tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "../evil", Mode: 0o644, Size: 4})
+	tw.Write([]byte("evil"))
+	tw.Close()
+	sum := sha256.Sum256(archive.Bytes())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(checksumHeader, hex.EncodeToString(sum[:]))
+		w.Write(archive.Bytes())
+	}))
+	defer server.Close()
+	c := NewClient(&ClientOptions{
+		Url:    strings.TrimPrefix(server.URL, "http://"),
+		Scheme: "http",
+	})
+
+	dir := filepath.Join(t.TempDir(), "out")
+	_, err := c.DownloadDir(context.Background(), &DownloadDirRequest{ID: uuid.NewString(), Path: "/etc", Dir: dir})
+	assert.ErrorContains(t, err, "outside of the directory")
+	_, err = os.Stat(filepath.Join(dir, "..", "evil"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestUploadFileRequestValidation(t *testing.T) {
+	type testCase struct {
+		request UploadFileRequest
+		passes  bool
+	}
+
+	cases := []testCase{
+		{
+			request: UploadFileRequest{ID: uuid.NewString(), Path: "/tmp/a", Body: strings.NewReader("a")},
+			passes:  true,
+		},
+		{
+			request: UploadFileRequest{ID: uuid.NewString(), Path: "tmp/a", Body: strings.NewReader("a")},
+			passes:  false,
+		},
+		{
+			request: UploadFileRequest{ID: uuid.NewString(), Path: "/tmp/a"},
+			passes:  false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("upload_file_validation_%s_%t", c.request.Path, c.passes), func(t *testing.T) {
+			err := c.request.Validate()
+			if c.passes {
+				assert.Nil(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
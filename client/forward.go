@@ -0,0 +1,261 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"sync"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/srepio/sdk/types"
+)
+
+// How much is read from a local connection before it is sent
+const forwardChunk = 32 * 1024
+
+type ForwardRequest struct {
+	ID string `json:"id" param:"id"`
+	// The port inside the play connections are forwarded to
+	Port uint16 `json:"port"`
+	// Where to listen, defaults to a free port on localhost
+	LocalAddr string `json:"-"`
+}
+
+func (r ForwardRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.ID, validation.Required, validation.Match(regexp.MustCompile(uuidRegex))),
+		validation.Field(&r.Port, validation.Required),
+	)
+}
+
+// Tunnels local TCP connections to a port inside a play. Every connection
+// shares one socket, data for a slow connection holds up the others.
+type Forwarder struct {
+	ln   net.Listener
+	sock Socket
+	port uint16
+
+	mu      *sync.Mutex
+	streams map[uint32]*forwardStream
+	next    uint32
+
+	done chan struct{}
+	once *sync.Once
+	err  error
+}
+
+// One forwarded connection, it ends once both sides have closed
+type forwardStream struct {
+	id         uint32
+	conn       net.Conn
+	sent       bool
+	received   bool
+	closedOnce *sync.Once
+}
+
+// Listen locally and forward connections to a port in a play until the
+// forwarder is closed, the context is cancelled or the play finishes
+func (c *Client) Forward(ctx context.Context, req *ForwardRequest) (*Forwarder, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	addr := req.LocalAddr
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+
+	sock, _, err := c.dialSocket(ctx, fmt.Sprintf("/plays/%s/forward", req.ID), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		sock.Close()
+		return nil, err
+	}
+
+	f := &Forwarder{
+		ln:      ln,
+		sock:    sock,
+		port:    req.Port,
+		mu:      &sync.Mutex{},
+		streams: map[uint32]*forwardStream{},
+		done:    make(chan struct{}),
+		once:    &sync.Once{},
+	}
+	go f.accept()
+	go f.read()
+	go func() {
+		select {
+		case <-ctx.Done():
+			f.close(ctx.Err())
+		case <-f.done:
+		}
+	}()
+	return f, nil
+}
+
+// The local address connections are accepted on
+func (f *Forwarder) Addr() net.Addr {
+	return f.ln.Addr()
+}
+
+// Stop listening and drop every forwarded connection
+func (f *Forwarder) Close() error {
+	f.close(nil)
+	return nil
+}
+
+// Closed once the forwarder has stopped
+func (f *Forwarder) Done() <-chan struct{} {
+	return f.done
+}
+
+// Why the forwarder stopped, nil while it is running or when it was closed.
+// ErrPlayFinished when the play ended.
+func (f *Forwarder) Err() error {
+	select {
+	case <-f.done:
+		return f.err
+	default:
+		return nil
+	}
+}
+
+func (f *Forwarder) close(err error) {
+	f.once.Do(func() {
+		f.err = err
+		close(f.done)
+		f.ln.Close()
+		f.sock.Close()
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for id, st := range f.streams {
+			st.conn.Close()
+			delete(f.streams, id)
+		}
+	})
+}
+
+func (f *Forwarder) accept() {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			f.close(err)
+			return
+		}
+
+		f.mu.Lock()
+		f.next++
+		st := &forwardStream{id: f.next, conn: conn, closedOnce: &sync.Once{}}
+		f.streams[st.id] = st
+		f.mu.Unlock()
+
+		if err := f.send(&types.ForwardOpenPayload{Stream: st.id, Port: f.port}); err != nil {
+			f.close(err)
+			return
+		}
+		go f.pump(st)
+	}
+}
+
+// Send what the local connection writes until it closes its side
+func (f *Forwarder) pump(st *forwardStream) {
+	buf := make([]byte, forwardChunk)
+	for {
+		n, err := st.conn.Read(buf)
+		if n > 0 {
+			data := append([]byte{}, buf[:n]...)
+			if werr := f.send(&types.ForwardDataPayload{Stream: st.id, Data: data}); werr != nil {
+				f.close(werr)
+				return
+			}
+		}
+		if err == nil {
+			continue
+		}
+
+		msg := &types.ForwardClosePayload{Stream: st.id}
+		if !errors.Is(err, io.EOF) {
+			msg.Error = err.Error()
+		}
+		f.send(msg)
+		f.finish(st, true, msg.Error != "")
+		return
+	}
+}
+
+func (f *Forwarder) read() {
+	for {
+		ev, err := f.sock.Read()
+		if err != nil {
+			f.close(err)
+			return
+		}
+		p, err := types.DecodeEvent(ev)
+		if err != nil {
+			continue
+		}
+
+		switch p := p.(type) {
+		case *types.ForwardDataPayload:
+			if st := f.stream(p.Stream); st != nil {
+				if _, err := st.conn.Write(p.Data); err != nil {
+					f.send(&types.ForwardClosePayload{Stream: st.id, Error: err.Error()})
+					f.finish(st, false, true)
+				}
+			}
+		case *types.ForwardClosePayload:
+			if st := f.stream(p.Stream); st != nil {
+				f.finish(st, false, p.Error != "")
+			}
+		case *types.PlayFinishedPayload:
+			f.close(ErrPlayFinished)
+			return
+		case *types.PingPayload:
+			f.send(&types.PongPayload{Data: p.Data})
+		}
+	}
+}
+
+func (f *Forwarder) stream(id uint32) *forwardStream {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.streams[id]
+}
+
+// Record that one side of a stream has closed, the connection is closed
+// once both have or straight away when it failed
+func (f *Forwarder) finish(st *forwardStream, sent, failed bool) {
+	f.mu.Lock()
+	if sent {
+		st.sent = true
+	} else {
+		st.received = true
+		// Let the local side see the end of the remote's data
+		if tcp, ok := st.conn.(*net.TCPConn); ok && !failed {
+			tcp.CloseWrite()
+		}
+	}
+	over := failed || (st.sent && st.received)
+	if over {
+		delete(f.streams, st.id)
+	}
+	f.mu.Unlock()
+
+	if over {
+		st.closedOnce.Do(func() { st.conn.Close() })
+	}
+}
+
+func (f *Forwarder) send(p types.Payload) error {
+	ev, err := types.EncodeEvent(p)
+	if err != nil {
+		return err
+	}
+	return f.sock.Write(ev)
+}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func port(t *testing.T, addr net.Addr) uint16 {
+	_, raw, err := net.SplitHostPort(addr.String())
+	require.Nil(t, err)
+	p, err := strconv.Atoi(raw)
+	require.Nil(t, err)
+	return uint16(p)
+}
+
+func TestForwardHTTP(t *testing.T) {
+	tc := prepareShell(t, nil)
+	web := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "hello from %s", r.URL.Path)
+	}))
+	defer web.Close()
+
+	f, err := tc.client.Forward(context.Background(), &ForwardRequest{ID: tc.playID, Port: port(t, web.Listener.Addr())})
+	require.Nil(t, err)
+	defer f.Close()
+
+	hc := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := hc.Get(fmt.Sprintf("http://%s/%d", f.Addr(), i))
+			if !assert.Nil(t, err) {
+				return
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, fmt.Sprintf("hello from /%d", i), string(body))
+		}()
+	}
+	wg.Wait()
+}
+
+func TestForwardHalfClose(t *testing.T) {
+	tc := prepareShell(t, nil)
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer echo.Close()
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+
+	f, err := tc.client.Forward(context.Background(), &ForwardRequest{ID: tc.playID, Port: port(t, echo.Addr())})
+	require.Nil(t, err)
+	defer f.Close()
+
+	conn, err := net.Dial("tcp", f.Addr().String())
+	require.Nil(t, err)
+	defer conn.Close()
+	conn.Write([]byte("bongo"))
+	conn.(*net.TCPConn).CloseWrite()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	out, err := io.ReadAll(conn)
+	require.Nil(t, err)
+	assert.Equal(t, "bongo", string(out))
+}
+
+func TestForwardToAClosedPort(t *testing.T) {
+	tc := prepareShell(t, nil)
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	closed.Close()
+
+	f, err := tc.client.Forward(context.Background(), &ForwardRequest{ID: tc.playID, Port: port(t, closed.Addr())})
+	require.Nil(t, err)
+	defer f.Close()
+
+	conn, err := net.Dial("tcp", f.Addr().String())
+	require.Nil(t, err)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, os.ErrDeadlineExceeded))
+}
+
+func TestForwardStopsWhenThePlayFinishes(t *testing.T) {
+	tc := prepareShell(t, nil)
+	f, err := tc.client.Forward(context.Background(), &ForwardRequest{ID: tc.playID, Port: 8080})
+	require.Nil(t, err)
+
+	_, err = tc.client.CheckPlay(context.Background(), &CheckPlayRequest{ID: tc.playID})
+	require.Nil(t, err)
+
+	select {
+	case <-f.Done():
+	case <-time.After(time.Second):
+		t.Fatal("forwarder didn't stop")
+	}
+	assert.ErrorIs(t, f.Err(), ErrPlayFinished)
+	_, err = net.Dial("tcp", f.Addr().String())
+	assert.Error(t, err)
+}
+
+func TestForwardRequestValidation(t *testing.T) {
+	assert.Error(t, ForwardRequest{ID: "bongo", Port: 80}.Validate())
+	assert.Error(t, ForwardRequest{ID: "6aac65e9-17d2-4a34-8503-490138aa3ed5"}.Validate())
+	assert.Nil(t, ForwardRequest{ID: "6aac65e9-17d2-4a34-8503-490138aa3ed5", Port: 80}.Validate())
+}
@@ -0,0 +1,28 @@
+package client
+
+import (
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/srepio/sdk/types"
+)
+
+// Whether the server agreed to base64 encoded events in the handshake, when
+// it did terminal data that isn't valid UTF-8 survives the trip
+func negotiatedBase64(resp *http.Response) bool {
+	return resp != nil && resp.Header.Get("Sec-Websocket-Protocol") == types.Base64Protocol
+}
+
+// Split off a multi-byte UTF-8 sequence that is cut short at the end of p, so
+// that it can be sent once the rest of it has been read
+func splitUTF8(p []byte) (complete, partial []byte) {
+	for i := len(p) - 1; i >= 0 && i > len(p)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(p[i]) {
+			if !utf8.FullRune(p[i:]) {
+				return p[:i], p[i:]
+			}
+			break
+		}
+	}
+	return p, nil
+}
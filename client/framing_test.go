@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/srepio/sdk/srepfake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitUTF8(t *testing.T) {
+	cases := []struct {
+		in       string
+		complete string
+		partial  string
+	}{
+		{in: "", complete: ""},
+		{in: "ls\n", complete: "ls\n"},
+		{in: "caf\xc3", complete: "caf", partial: "\xc3"},
+		{in: "caf\xc3\xa9", complete: "caf\xc3\xa9"},
+		{in: "\xe2\x82", complete: "", partial: "\xe2\x82"},
+		{in: "1\xf0\x9f\x98", complete: "1", partial: "\xf0\x9f\x98"},
+		{in: "bad\xff", complete: "bad\xff"},
+		{in: "\x80\x80\x80\x80", complete: "\x80\x80\x80\x80"},
+	}
+
+	for _, c := range cases {
+		complete, partial := splitUTF8([]byte(c.in))
+		assert.Equal(t, c.complete, string(complete), "%q", c.in)
+		assert.Equal(t, c.partial, string(partial), "%q", c.in)
+	}
+}
+
+func TestShellNegotiatesBase64(t *testing.T) {
+	tc := prepareShell(t, nil)
+
+	s, err := tc.client.OpenShell(context.Background(), &GetShellRequest{ID: tc.playID}, nil)
+	require.Nil(t, err)
+	defer s.Close()
+	assert.True(t, s.binary())
+
+	// Not valid UTF-8, so it would be mangled by JSON without base64
+	_, err = s.Write([]byte{0xff, 0x00, 0xfe, '\n'})
+	require.Nil(t, err)
+	readUntil(t, s, "\xff\x00\xfe\n")
+}
+
+func TestShellHoldsBackSplitCharactersWithoutBase64(t *testing.T) {
+	tc := prepareShell(t, &srepfake.Options{TextOnly: true})
+
+	s, err := tc.client.OpenShell(context.Background(), &GetShellRequest{ID: tc.playID}, nil)
+	require.Nil(t, err)
+	defer s.Close()
+	assert.False(t, s.binary())
+
+	_, err = s.Write([]byte("echo caf\xc3"))
+	require.Nil(t, err)
+	_, err = s.Write([]byte("\xa9\n"))
+	require.Nil(t, err)
+	readUntil(t, s, "echo café\n")
+}
@@ -0,0 +1,127 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/srepio/sdk/types"
+)
+
+const (
+	defaultKeepaliveInterval     = time.Second * 15
+	defaultKeepaliveWriteTimeout = time.Second * 10
+)
+
+// Controls the heartbeats a shell sends to notice when its connection has
+// died without being closed
+type KeepaliveOptions struct {
+	// How often heartbeats are sent, defaults to 15s
+	Interval time.Duration
+	// The connection is treated as dead once nothing has been received for
+	// this long, defaults to three intervals
+	ReadTimeout time.Duration
+	// Writes that block for longer than this fail, defaults to 10s
+	WriteTimeout time.Duration
+}
+
+func (k *KeepaliveOptions) interval() time.Duration {
+	if k.Interval <= 0 {
+		return defaultKeepaliveInterval
+	}
+	return k.Interval
+}
+
+func (k *KeepaliveOptions) readTimeout() time.Duration {
+	if k.ReadTimeout <= 0 {
+		return k.interval() * 3
+	}
+	return k.ReadTimeout
+}
+
+func (k *KeepaliveOptions) writeTimeout() time.Duration {
+	if k.WriteTimeout <= 0 {
+		return defaultKeepaliveWriteTimeout
+	}
+	return k.WriteTimeout
+}
+
+// The most recent round trip time to the server, zero until a heartbeat has
+// been answered
+func (s *ShellSession) RTT() time.Duration {
+	return time.Duration(s.rtt.Load())
+}
+
+// Set up timeouts and pong handling on a socket before it is read from
+func (s *ShellSession) prepare(sock Socket) {
+	s.heard.Store(int64(time.Since(s.epoch)))
+	if s.keepalive == nil {
+		return
+	}
+
+	if ts, ok := sock.(timeoutSocket); ok {
+		ts.SetTimeouts(s.keepalive.readTimeout(), s.keepalive.writeTimeout())
+	}
+	if ps, ok := sock.(pingSocket); ok {
+		ps.SetPongHandler(s.pong)
+	}
+}
+
+// Handle the answer to a heartbeat, the payload is when it was sent
+func (s *ShellSession) pong(payload []byte) {
+	now := time.Since(s.epoch)
+	s.heard.Store(int64(now))
+
+	sent, err := strconv.ParseInt(string(payload), 10, 64)
+	if err != nil || sent <= 0 || sent > int64(now) {
+		return
+	}
+	s.rtt.Store(int64(now) - sent)
+}
+
+// Send heartbeats until the session ends. Sockets that can't time out reads
+// are closed once they have been silent for too long.
+func (s *ShellSession) heartbeat() {
+	ticker := time.NewTicker(s.keepalive.interval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.Lock()
+		sock := s.sock
+		s.mu.Unlock()
+		if sock == nil {
+			continue
+		}
+
+		now := time.Since(s.epoch)
+		if _, ok := sock.(timeoutSocket); !ok && now-time.Duration(s.heard.Load()) > s.keepalive.readTimeout() {
+			s.expired.Store(true)
+			sock.Close()
+			continue
+		}
+
+		payload := strconv.FormatInt(int64(now), 10)
+		if ps, ok := sock.(pingSocket); ok {
+			ps.Ping([]byte(payload))
+		}
+		// A failed write surfaces as a failed read on the same socket
+		s.write(sock, &types.PingPayload{Data: payload})
+	}
+}
+
+// Report reads that failed because the connection went quiet as such
+func (s *ShellSession) deadConnection(err error) error {
+	var ne net.Error
+	if s.expired.Swap(false) || (errors.As(err, &ne) && ne.Timeout()) {
+		return fmt.Errorf("%w: nothing received for %s", ErrDeadConnection, s.keepalive.readTimeout())
+	}
+	return err
+}
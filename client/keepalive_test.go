@@ -0,0 +1,191 @@
+package client
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/srepio/sdk/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShellMeasuresRTT(t *testing.T) {
+	tc := prepareShell(t, nil)
+
+	s, err := tc.client.OpenShell(context.Background(), &GetShellRequest{ID: tc.playID}, &ShellOptions{
+		Keepalive: &KeepaliveOptions{Interval: time.Millisecond * 20},
+	})
+	require.Nil(t, err)
+	defer s.Close()
+
+	assert.Eventually(t, func() bool {
+		return s.RTT() > 0
+	}, time.Second, time.Millisecond*10)
+}
+
+func TestShellDetectsDeadConnections(t *testing.T) {
+	tc := prepareShell(t, nil)
+
+	s, err := tc.client.OpenShell(context.Background(), &GetShellRequest{ID: tc.playID}, &ShellOptions{
+		Keepalive: &KeepaliveOptions{
+			Interval:    time.Millisecond * 20,
+			ReadTimeout: time.Millisecond * 100,
+		},
+	})
+	require.Nil(t, err)
+
+	// Answered heartbeats keep the connection alive past the read timeout
+	time.Sleep(time.Millisecond * 200)
+	select {
+	case <-s.Done():
+		t.Fatalf("session ended while the server was responding: %v", s.Err())
+	default:
+	}
+
+	tc.server.StallConnections(tc.playID)
+	select {
+	case <-s.Done():
+	case <-time.After(time.Second * 5):
+		t.Fatal("dead connection was not detected")
+	}
+	assert.Equal(t, CloseNetworkError, s.Reason())
+	assert.ErrorIs(t, s.Err(), ErrDeadConnection)
+}
+
+// A socket that never receives anything and has no timeouts of its own
+type silentSocket struct {
+	closed chan struct{}
+	once   *sync.Once
+}
+
+func (s *silentSocket) Read() (*types.SocketEvent, error) {
+	<-s.closed
+	return nil, io.ErrClosedPipe
+}
+
+func (s *silentSocket) Write(msg *types.SocketEvent) error {
+	return nil
+}
+
+func (s *silentSocket) Close() error {
+	s.once.Do(func() { close(s.closed) })
+	return nil
+}
+
+func TestShellDetectsDeadConnectionsWithoutDeadlines(t *testing.T) {
+	sock := &silentSocket{closed: make(chan struct{}), once: &sync.Once{}}
+	s := NewShellSession(context.Background(), sock, &ShellOptions{
+		Keepalive: &KeepaliveOptions{
+			Interval:    time.Millisecond * 10,
+			ReadTimeout: time.Millisecond * 50,
+		},
+	})
+
+	select {
+	case <-s.Done():
+	case <-time.After(time.Second * 5):
+		t.Fatal("dead connection was not detected")
+	}
+	assert.ErrorIs(t, s.Err(), ErrDeadConnection)
+}
+
+// A socket that reads the events it is fed and keeps what is written to it
+type scriptedSocket struct {
+	in     chan *types.SocketEvent
+	out    chan *types.SocketEvent
+	closed chan struct{}
+	once   *sync.Once
+}
+
+func (s *scriptedSocket) Read() (*types.SocketEvent, error) {
+	select {
+	case ev := <-s.in:
+		return ev, nil
+	case <-s.closed:
+		return nil, io.ErrClosedPipe
+	}
+}
+
+func (s *scriptedSocket) Write(msg *types.SocketEvent) error {
+	s.out <- msg
+	return nil
+}
+
+func (s *scriptedSocket) Close() error {
+	s.once.Do(func() { close(s.closed) })
+	return nil
+}
+
+func TestShellAnswersPingsWhileOutputIsNotDrained(t *testing.T) {
+	chunks := sessionOutputBuffer * 2
+	sock := &scriptedSocket{
+		in:     make(chan *types.SocketEvent, chunks+1),
+		out:    make(chan *types.SocketEvent, 1),
+		closed: make(chan struct{}),
+		once:   &sync.Once{},
+	}
+	s := NewShellSession(context.Background(), sock, nil)
+	defer s.Close()
+
+	for i := 0; i < chunks; i++ {
+		ev, err := types.EncodeEvent(&types.OutputPayload{Data: []byte("x")})
+		require.Nil(t, err)
+		sock.in <- ev
+	}
+	ping, err := types.EncodeEvent(&types.PingPayload{Data: "hello"})
+	require.Nil(t, err)
+	sock.in <- ping
+
+	select {
+	case ev := <-sock.out:
+		payload, err := types.DecodeEvent(ev)
+		require.Nil(t, err)
+		assert.Equal(t, &types.PongPayload{Data: "hello"}, payload)
+	case <-time.After(time.Second * 5):
+		t.Fatal("ping was not answered")
+	}
+
+	// The output held back while nobody was reading is still delivered
+	for i := 0; i < chunks; i++ {
+		select {
+		case data := <-s.Output():
+			assert.Equal(t, []byte("x"), data)
+		case <-time.After(time.Second * 5):
+			t.Fatalf("only received %d of %d chunks", i, chunks)
+		}
+	}
+	select {
+	case <-s.Done():
+		t.Fatalf("session ended: %v", s.Err())
+	default:
+	}
+}
+
+func TestShellEndsWhenOutputOverflows(t *testing.T) {
+	chunk := make([]byte, 64<<10)
+	chunks := sessionOutputBacklog/len(chunk) + sessionOutputBuffer + 2
+	sock := &scriptedSocket{
+		in:     make(chan *types.SocketEvent, chunks),
+		out:    make(chan *types.SocketEvent, 1),
+		closed: make(chan struct{}),
+		once:   &sync.Once{},
+	}
+	s := NewShellSession(context.Background(), sock, nil)
+
+	for i := 0; i < chunks; i++ {
+		ev, err := types.EncodeEvent(&types.OutputPayload{Data: chunk})
+		require.Nil(t, err)
+		sock.in <- ev
+	}
+
+	select {
+	case <-s.Done():
+	case <-time.After(time.Second * 5):
+		t.Fatal("session did not end")
+	}
+	assert.Equal(t, CloseLocalError, s.Reason())
+	assert.ErrorIs(t, s.Err(), ErrOutputOverflow)
+}
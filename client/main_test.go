@@ -14,8 +14,12 @@ type apiTestCase struct {
 	Body    string
 	Code    int
 	Headers map[string]string
-	Extra   func(*testing.T)
-	Errors  bool
+	// Sent as the response's Content-Type when set
+	ContentType string
+	Extra       func(*testing.T)
+	Errors      bool
+	// Adjusts the client options, such as pointing them at a cassette
+	Configure func(*ClientOptions) *ClientOptions
 }
 
 func (a *apiTestCase) Prepare(t *testing.T) (*httptest.Server, *Client) {
@@ -33,13 +37,19 @@ func (a *apiTestCase) Prepare(t *testing.T) (*httptest.Server, *Client) {
 			a.Extra(t)
 		}
 
+		if a.ContentType != "" {
+			w.Header().Set("Content-Type", a.ContentType)
+		}
 		w.WriteHeader(a.Code)
 		w.Write([]byte(a.Body))
 	}))
 
-	c := NewClient(&ClientOptions{
+	opts := &ClientOptions{
 		Url:    strings.TrimPrefix(server.URL, "http://"),
 		Scheme: "http",
-	})
-	return server, c
+	}
+	if a.Configure != nil {
+		opts = a.Configure(opts)
+	}
+	return server, NewClient(opts)
 }
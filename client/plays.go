@@ -4,15 +4,10 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"net/url"
-	"os"
 	"regexp"
-	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
-	"github.com/gorilla/websocket"
 	"github.com/srepio/sdk/types"
-	"golang.org/x/crypto/ssh/terminal"
 )
 
 type StartPlayRequest struct {
@@ -105,129 +100,18 @@ type GetShellRequest struct {
 	ID   string `json:"id" param:"id"`
 	Rows uint16 `json:"rows"`
 	Cols uint16 `json:"cols"`
+	// The terminal to connect to, it is created if it doesn't exist.
+	// Defaults to types.DefaultTerminal.
+	Terminal string `json:"terminal,omitempty"`
 }
 
 func (r GetShellRequest) Validate() error {
 	return validation.ValidateStruct(&r,
 		validation.Field(&r.ID, validation.Required, validation.Match(regexp.MustCompile(uuidRegex))),
+		validation.Field(&r.Terminal, validation.Match(regexp.MustCompile(terminalRegex))),
 	)
 }
 
-func (c *Client) GetShell(ctx context.Context, req *GetShellRequest, stdin *os.File, stdout *os.File) error {
-	var scheme string
-	if c.Options.Scheme == "https" {
-		scheme = "wss"
-	} else {
-		scheme = "ws"
-	}
-
-	url := url.URL{
-		Scheme: scheme,
-		Host:   c.Options.Url,
-		Path:   fmt.Sprintf("/plays/%s/shell", req.ID),
-	}
-	headers := make(http.Header)
-	headers.Add("Authorization", fmt.Sprintf("Bearer %s", c.Options.Token))
-
-	wso, resp, err := websocket.DefaultDialer.Dial(url.String(), headers)
-	if err != nil {
-		if resp.StatusCode == http.StatusTooEarly {
-			return ErrTooEarly
-		}
-		return fmt.Errorf("%v: %d", err, resp.StatusCode)
-	}
-	defer wso.Close()
-	sock := newWs(wso)
-
-	go func() {
-		var oldRows int
-		var oldCols int
-
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				cols, rows, err := terminal.GetSize(int(stdout.Fd()))
-				if err != nil {
-					fmt.Println(err)
-					return
-				}
-				if oldRows != rows || oldCols != cols {
-					msg := &types.SocketEvent{
-						Type:    types.Resize,
-						Content: fmt.Sprintf("%d,%d", rows, cols),
-					}
-					sock.Write(msg)
-				}
-				time.Sleep(time.Second * 1)
-			}
-		}
-	}()
-
-	done := make(chan struct{})
-
-	go func() {
-		defer close(done)
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				msg, err := sock.Read()
-				if err != nil {
-					if websocket.IsUnexpectedCloseError(err) {
-						return
-					}
-					fmt.Println(err)
-					return
-				}
-
-				if msg.Type == types.Ping {
-					if err := sock.Write(&types.SocketEvent{Type: types.Pong}); err != nil {
-						fmt.Println(err)
-						return
-					}
-				} else {
-					stdout.Write([]byte(msg.Content))
-				}
-			}
-		}
-	}()
-
-	go func() {
-		buffer := make([]byte, 1024)
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				n, err := stdin.Read(buffer)
-				if err != nil {
-					fmt.Println(err)
-					return
-				}
-				if n == 0 {
-					continue
-				}
-				data := make([]byte, n)
-				copy(data, buffer)
-				msg := &types.SocketEvent{
-					Type:    types.Input,
-					Content: string(data),
-				}
-				if err := sock.Write(msg); err != nil {
-					fmt.Println(err)
-					return
-				}
-			}
-		}
-	}()
-
-	<-done
-	return nil
-}
-
 type GetActivePlayRequest struct{}
 
 func (r GetActivePlayRequest) Validate() error {
@@ -234,6 +234,46 @@ func TestFindPlayRequestValidation(t *testing.T) {
 	}
 }
 
+func TestExecRequestValidation(t *testing.T) {
+	type testCase struct {
+		request ExecRequest
+		passes  bool
+	}
+
+	cases := []testCase{
+		{
+			request: ExecRequest{
+				ID:      uuid.NewString(),
+				Command: "kubectl get pods",
+			},
+			passes: true,
+		},
+		{
+			request: ExecRequest{
+				ID: uuid.NewString(),
+			},
+			passes: false,
+		},
+		{
+			request: ExecRequest{
+				Command: "kubectl get pods",
+			},
+			passes: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("exec_validation_%s_%t", c.request.Command, c.passes), func(t *testing.T) {
+			err := c.request.Validate()
+			if c.passes {
+				assert.Nil(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
 // func TestGetShell(t *testing.T) {
 // 	cases := []apiTestCase{
 // 		{
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// A terminal switched to raw mode, Restore puts it back how it was and is
+// safe to call more than once
+type rawTerminal struct {
+	fd    int
+	state *terminal.State
+	once  *sync.Once
+	stop  chan struct{}
+}
+
+// Switch the file to raw mode when it is a terminal. The terminal is restored
+// when ctx is cancelled or the process is sent a terminating signal, in which
+// case the signal is raised again once the terminal has been restored.
+func makeRaw(ctx context.Context, f *os.File) (*rawTerminal, error) {
+	fd := int(f.Fd())
+	if !terminal.IsTerminal(fd) {
+		return &rawTerminal{once: &sync.Once{}}, nil
+	}
+	state, err := terminal.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &rawTerminal{
+		fd:    fd,
+		state: state,
+		once:  &sync.Once{},
+		stop:  make(chan struct{}),
+	}
+
+	sigs := make(chan os.Signal, 1)
+	// Notify with no signals would catch every signal
+	if len(restoreSignals) > 0 {
+		signal.Notify(sigs, restoreSignals...)
+	}
+	go func() {
+		defer signal.Stop(sigs)
+		select {
+		case <-t.stop:
+		case <-ctx.Done():
+			t.Restore()
+		case sig := <-sigs:
+			t.Restore()
+			signal.Stop(sigs)
+			reraise(sig)
+		}
+	}()
+
+	return t, nil
+}
+
+func (t *rawTerminal) Restore() {
+	t.once.Do(func() {
+		if t.state == nil {
+			return
+		}
+		terminal.Restore(t.fd, t.state)
+		close(t.stop)
+	})
+}
+
+// Restore the terminal if the calling goroutine panics, then carry on
+// panicking. Must be deferred.
+func (t *rawTerminal) restoreOnPanic() {
+	if r := recover(); r != nil {
+		t.Restore()
+		panic(r)
+	}
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+// Open a pseudo terminal and return its slave end
+func openPty(t *testing.T) *os.File {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		t.Skipf("no pty support: %v", err)
+	}
+	t.Cleanup(func() { master.Close() })
+
+	fd := int(master.Fd())
+	require.Nil(t, unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0))
+	n, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	require.Nil(t, err)
+
+	slave, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|unix.O_NOCTTY, 0)
+	require.Nil(t, err)
+	t.Cleanup(func() { slave.Close() })
+	return slave
+}
+
+func isCanonical(t *testing.T, f *os.File) bool {
+	termios, err := unix.IoctlGetTermios(int(f.Fd()), unix.TCGETS)
+	require.Nil(t, err)
+	return termios.Lflag&unix.ICANON != 0
+}
+
+func TestMakeRawRestores(t *testing.T) {
+	tty := openPty(t)
+	require.True(t, isCanonical(t, tty))
+
+	raw, err := makeRaw(context.Background(), tty)
+	require.Nil(t, err)
+	assert.False(t, isCanonical(t, tty))
+
+	raw.Restore()
+	raw.Restore()
+	assert.True(t, isCanonical(t, tty))
+}
+
+func TestMakeRawRestoresOnCancel(t *testing.T) {
+	tty := openPty(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	_, err := makeRaw(ctx, tty)
+	require.Nil(t, err)
+	assert.False(t, isCanonical(t, tty))
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		return isCanonical(t, tty)
+	}, time.Second, time.Millisecond*10)
+}
+
+func TestMakeRawRestoresOnPanic(t *testing.T) {
+	tty := openPty(t)
+
+	raw, err := makeRaw(context.Background(), tty)
+	require.Nil(t, err)
+
+	assert.Panics(t, func() {
+		defer raw.restoreOnPanic()
+		panic("bongo")
+	})
+	assert.True(t, isCanonical(t, tty))
+}
+
+func TestMakeRawIgnoresFilesThatAreNotTerminals(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.Nil(t, err)
+	defer r.Close()
+	defer w.Close()
+
+	raw, err := makeRaw(context.Background(), r)
+	require.Nil(t, err)
+	raw.Restore()
+}
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	defaultReconnectAttempts     = 10
+	defaultReconnectInitialDelay = time.Millisecond * 500
+	defaultReconnectMaxDelay     = time.Second * 30
+)
+
+// Controls how a shell reconnects after its connection drops
+type ReconnectOptions struct {
+	// Give up after this many failed attempts in a row, defaults to 10
+	MaxAttempts int
+	// The delay before the first attempt, doubled after each failure.
+	// Defaults to 500ms.
+	InitialDelay time.Duration
+	// The longest to wait between attempts, defaults to 30s
+	MaxDelay time.Duration
+	// Called before each attempt and once the shell is connected again
+	OnReconnect func(ev ReconnectEvent)
+}
+
+// Reports the progress of a reconnect
+type ReconnectEvent struct {
+	// Counts up from 1 for each attempt since the connection dropped
+	Attempt int
+	// Why the connection dropped or the previous attempt failed
+	Err error
+	// How long until the attempt is made
+	Delay time.Duration
+	// Set once the shell has been reconnected
+	Reconnected bool
+}
+
+func (r *ReconnectOptions) attempts() int {
+	if r.MaxAttempts <= 0 {
+		return defaultReconnectAttempts
+	}
+	return r.MaxAttempts
+}
+
+// Exponential backoff with jitter so that a server restart isn't met with
+// every client reconnecting at once
+func (r *ReconnectOptions) backoff(attempt int) time.Duration {
+	initial, max := r.InitialDelay, r.MaxDelay
+	if initial <= 0 {
+		initial = defaultReconnectInitialDelay
+	}
+	if max <= 0 {
+		max = defaultReconnectMaxDelay
+	}
+
+	delay := initial
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+func (r *ReconnectOptions) emit(ev ReconnectEvent) {
+	if r.OnReconnect != nil {
+		r.OnReconnect(ev)
+	}
+}
+
+// The status code the server rejected a shell handshake with
+type handshakeError struct {
+	err    error
+	status int
+}
+
+func (e *handshakeError) Error() string {
+	return fmt.Sprintf("%v: %d", e.err, e.status)
+}
+
+func (e *handshakeError) Unwrap() error {
+	return e.err
+}
+
+// Whether a dropped connection is worth reconnecting
+func shouldReconnect(reason CloseReason, err error) bool {
+	if reason == CloseNetworkError {
+		return true
+	}
+	return websocket.IsCloseError(err, websocket.CloseGoingAway, websocket.CloseServiceRestart, websocket.CloseTryAgainLater)
+}
+
+// Whether dialing the shell failed in a way that may succeed later, the play
+// still booting, the server being unreachable or temporarily unavailable
+func shouldRedial(err error) bool {
+	if errors.Is(err, ErrTooEarly) {
+		return true
+	}
+	var he *handshakeError
+	if errors.As(err, &he) {
+		return he.status >= http.StatusInternalServerError
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// Dial the shell, waiting for the play to boot when reconnecting is enabled
+func (c *Client) connectShell(ctx context.Context, dial func(ctx context.Context) (Socket, bool, error), r *ReconnectOptions) (Socket, bool, error) {
+	sock, base64, err := dial(ctx)
+	if r == nil {
+		return sock, base64, err
+	}
+
+	for attempt := 1; errors.Is(err, ErrTooEarly) && attempt <= r.attempts(); attempt++ {
+		select {
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		case <-time.After(r.backoff(attempt)):
+		}
+		sock, base64, err = dial(ctx)
+	}
+	return sock, base64, err
+}
+
+// Replace a dropped socket, the session is closed if it can't be
+func (s *ShellSession) reconnect(cause error) (Socket, bool) {
+	err := cause
+	for attempt := 1; attempt <= s.retry.attempts(); attempt++ {
+		delay := s.retry.backoff(attempt)
+		s.retry.emit(ReconnectEvent{Attempt: attempt, Err: err, Delay: delay})
+
+		select {
+		case <-s.done:
+			return nil, false
+		case <-time.After(delay):
+		}
+
+		sock, base64, derr := s.dial(s.ctx)
+		if derr == nil {
+			if !s.attach(sock, base64) {
+				return nil, false
+			}
+			s.retry.emit(ReconnectEvent{Attempt: attempt, Reconnected: true})
+			return sock, true
+		}
+
+		var he *handshakeError
+		switch {
+		case errors.As(derr, &he) && he.status == http.StatusGone:
+			s.close(ClosePlayFinished, nil)
+			return nil, false
+		case !shouldRedial(derr):
+			s.close(CloseServer, derr)
+			return nil, false
+		}
+		err = derr
+	}
+
+	s.close(CloseNetworkError, fmt.Errorf("gave up reconnecting after %d attempts: %w", s.retry.attempts(), err))
+	return nil, false
+}
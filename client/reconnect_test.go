@@ -0,0 +1,148 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/srepio/sdk/srepfake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type reconnectEvents struct {
+	mu     *sync.Mutex
+	events []ReconnectEvent
+}
+
+func (r *reconnectEvents) record(ev ReconnectEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, ev)
+}
+
+func (r *reconnectEvents) reconnected() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.events) > 0 && r.events[len(r.events)-1].Reconnected
+}
+
+func (r *reconnectEvents) len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.events)
+}
+
+func readUntil(t *testing.T, s *ShellSession, suffix string) {
+	output := ""
+	timeout := time.After(time.Second * 5)
+	for !strings.HasSuffix(output, suffix) {
+		select {
+		case data := <-s.Output():
+			output += string(data)
+		case <-timeout:
+			t.Fatalf("%q not received, got %q", suffix, output)
+		}
+	}
+}
+
+func TestShellReconnectsWhenTheConnectionDrops(t *testing.T) {
+	tc := prepareShell(t, nil)
+	events := &reconnectEvents{mu: &sync.Mutex{}}
+
+	s, err := tc.client.OpenShell(context.Background(), &GetShellRequest{ID: tc.playID, Rows: 30, Cols: 100}, &ShellOptions{
+		Reconnect: &ReconnectOptions{
+			InitialDelay: time.Millisecond * 10,
+			OnReconnect:  events.record,
+		},
+	})
+	require.Nil(t, err)
+	defer s.Close()
+
+	tc.server.DropConnections(tc.playID)
+	assert.Eventually(t, events.reconnected, time.Second*5, time.Millisecond*10)
+	assert.Equal(t, 1, events.events[0].Attempt)
+	assert.NotNil(t, events.events[0].Err)
+
+	_, err = s.Write([]byte("pwd\n"))
+	require.Nil(t, err)
+	readUntil(t, s, "pwd\n")
+
+	rows, cols := tc.server.ShellSize(tc.playID)
+	assert.Equal(t, uint16(30), rows)
+	assert.Equal(t, uint16(100), cols)
+
+	select {
+	case <-s.Done():
+		t.Fatal("session ended after reconnecting")
+	default:
+	}
+}
+
+func TestShellWaitsForThePlayToBoot(t *testing.T) {
+	tc := prepareShell(t, &srepfake.Options{BootTime: time.Minute})
+
+	_, err := tc.client.OpenShell(context.Background(), &GetShellRequest{ID: tc.playID}, nil)
+	require.ErrorIs(t, err, ErrTooEarly)
+
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		tc.server.Advance(time.Minute)
+	}()
+	s, err := tc.client.OpenShell(context.Background(), &GetShellRequest{ID: tc.playID}, &ShellOptions{
+		Reconnect: &ReconnectOptions{InitialDelay: time.Millisecond * 10},
+	})
+	require.Nil(t, err)
+	s.Close()
+}
+
+func TestShellDoesNotReconnectOncePlayFinished(t *testing.T) {
+	tc := prepareShell(t, nil)
+	events := &reconnectEvents{mu: &sync.Mutex{}}
+
+	s, err := tc.client.OpenShell(context.Background(), &GetShellRequest{ID: tc.playID}, &ShellOptions{
+		Reconnect: &ReconnectOptions{
+			InitialDelay: time.Millisecond * 10,
+			OnReconnect:  events.record,
+		},
+	})
+	require.Nil(t, err)
+
+	_, err = tc.client.CheckPlay(context.Background(), &CheckPlayRequest{ID: tc.playID})
+	require.Nil(t, err)
+
+	select {
+	case <-s.Done():
+	case <-time.After(time.Second * 5):
+		t.Fatal("session did not finish")
+	}
+	assert.Equal(t, ClosePlayFinished, s.Reason())
+	assert.Nil(t, s.Err())
+	assert.Equal(t, 0, events.len())
+}
+
+func TestShellGivesUpReconnecting(t *testing.T) {
+	tc := prepareShell(t, nil)
+
+	s, err := tc.client.OpenShell(context.Background(), &GetShellRequest{ID: tc.playID}, &ShellOptions{
+		Reconnect: &ReconnectOptions{
+			MaxAttempts:  2,
+			InitialDelay: time.Millisecond * 10,
+		},
+	})
+	require.Nil(t, err)
+
+	tc.server.DropConnections(tc.playID)
+	tc.server.Close()
+
+	select {
+	case <-s.Done():
+	case <-time.After(time.Second * 5):
+		t.Fatal("session did not give up")
+	}
+	var serr *ShellError
+	require.ErrorAs(t, s.Err(), &serr)
+	assert.Equal(t, CloseNetworkError, serr.Reason)
+}
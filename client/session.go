@@ -0,0 +1,681 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/srepio/sdk/types"
+)
+
+type CloseReason string
+
+const (
+	// The caller ended the session by closing stdin or cancelling the context
+	CloseUserExit CloseReason = "user_exit"
+	// The play was checked, cancelled or otherwise finished
+	ClosePlayFinished CloseReason = "play_finished"
+	// The server closed the socket
+	CloseServer CloseReason = "server_close"
+	// The connection to the server failed
+	CloseNetworkError CloseReason = "network_error"
+	// Reading stdin or writing to stdout failed
+	CloseLocalError CloseReason = "local_error"
+)
+
+const (
+	// How many chunks of output are buffered in the Output channel
+	sessionOutputBuffer = 64
+	// How many bytes of output are queued behind a full Output channel before
+	// the session is ended with ErrOutputOverflow
+	sessionOutputBacklog = 4 << 20
+)
+
+// Returned when a shell session ends because something failed
+type ShellError struct {
+	Reason CloseReason
+	Err    error
+}
+
+func (e *ShellError) Error() string {
+	return fmt.Sprintf("shell closed (%s): %v", e.Reason, e.Err)
+}
+
+func (e *ShellError) Unwrap() error {
+	return e.Err
+}
+
+// A shell on a play that is driven programmatically. Output can be consumed
+// either with Read or from the Output channel, but not both. The session runs
+// until Close is called, its context is cancelled or the connection ends.
+type ShellSession struct {
+	// The connected socket, nil while reconnecting
+	sock Socket
+	// Closed once sock is set
+	ready chan struct{}
+	mu    *sync.Mutex
+	// Whether sock carries base64 encoded events
+	base64 bool
+	dial   func(ctx context.Context) (Socket, bool, error)
+	retry  *ReconnectOptions
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Heartbeats are timed relative to epoch so the monotonic clock is used
+	keepalive *KeepaliveOptions
+	epoch     time.Time
+	rtt       atomic.Int64
+	heard     atomic.Int64
+	expired   atomic.Bool
+
+	// Input that ends part way through a UTF-8 sequence
+	partial []byte
+	writeMu *sync.Mutex
+
+	out     chan []byte
+	pending []byte
+	// Output waiting for room in out, kept apart so the socket is still read
+	// and pings answered while the consumer falls behind
+	backlog   [][]byte
+	queued    int
+	backlogMu *sync.Mutex
+	wake      chan struct{}
+	readMu    *sync.Mutex
+	resize    func(rows, cols uint16) error
+	rows      uint16
+	cols      uint16
+
+	play     atomic.Pointer[types.Play]
+	finish   atomic.Pointer[types.PlayFinishedPayload]
+	onPlay   func(play *types.Play)
+	onFinish func(ev *types.PlayFinishedPayload)
+	onClose  func(reason CloseReason, err error)
+	recorder ShellRecorder
+	onResize func(rows, cols uint16)
+	// Spectators can't send input or resize the terminal
+	readOnly bool
+
+	done     chan struct{}
+	once     *sync.Once
+	reason   CloseReason
+	err      error
+	finished atomic.Bool
+}
+
+// Open a shell session for the play. The remote terminal starts at the size
+// in the request and follows opts.Size when set, RawMode is ignored as there
+// is no local terminal. Cancelling ctx closes the session.
+func (c *Client) OpenShell(ctx context.Context, req *GetShellRequest, opts *ShellOptions) (*ShellSession, error) {
+	if opts == nil {
+		opts = &ShellOptions{}
+	}
+
+	dial := func(ctx context.Context) (Socket, bool, error) {
+		return c.dialShell(ctx, req)
+	}
+	sock, base64, err := c.connectShell(ctx, dial, opts.Reconnect)
+	if err != nil {
+		return nil, err
+	}
+
+	s := newShellSession(sock, opts)
+	s.base64 = base64
+	if opts.Reconnect != nil {
+		s.retry = opts.Reconnect
+		s.dial = dial
+	}
+	s.start(ctx)
+	s.Resize(req.Rows, req.Cols)
+	if opts.Size != nil {
+		go s.follow(opts.Size)
+	}
+	return s, nil
+}
+
+// Run a shell session over an already connected socket, useful for custom
+// transports and tests. Reconnect is ignored as there is nothing to redial.
+func NewShellSession(ctx context.Context, sock Socket, opts *ShellOptions) *ShellSession {
+	if opts == nil {
+		opts = &ShellOptions{}
+	}
+	s := newShellSession(sock, opts)
+	s.start(ctx)
+	if opts.Size != nil {
+		go s.follow(opts.Size)
+	}
+	return s
+}
+
+func newShellSession(sock Socket, opts *ShellOptions) *ShellSession {
+	s := &ShellSession{
+		sock:      sock,
+		ready:     make(chan struct{}),
+		mu:        &sync.Mutex{},
+		out:       make(chan []byte, sessionOutputBuffer),
+		backlogMu: &sync.Mutex{},
+		wake:      make(chan struct{}, 1),
+		readMu:    &sync.Mutex{},
+		writeMu:   &sync.Mutex{},
+		onClose:   opts.OnClose,
+		recorder:  opts.Recorder,
+		onPlay:    opts.OnActivePlay,
+		onFinish:  opts.OnPlayFinished,
+		onResize:  opts.OnResize,
+		keepalive: opts.Keepalive,
+		epoch:     time.Now(),
+		done:      make(chan struct{}),
+		once:      &sync.Once{},
+	}
+	close(s.ready)
+	s.resize = resizer(sessionSocket{s})
+	return s
+}
+
+func (s *ShellSession) start(ctx context.Context) {
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.prepare(s.sock)
+	go s.readLoop(s.sock)
+	go s.deliver()
+	if s.keepalive != nil {
+		go s.heartbeat()
+	}
+	go func() {
+		select {
+		case <-s.done:
+		case <-s.ctx.Done():
+			s.close(CloseUserExit, ctx.Err())
+		}
+	}()
+}
+
+// Send input to the play
+func (s *ShellSession) Write(p []byte) (int, error) {
+	if s.readOnly {
+		return 0, ErrReadOnly
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	data := append(s.partial, p...)
+	s.partial = nil
+	if !s.binary() {
+		// Without base64 a multi-byte character split across writes would be
+		// mangled by JSON, so the start of it is held back
+		var partial []byte
+		data, partial = splitUTF8(data)
+		s.partial = append(s.partial, partial...)
+	}
+	if len(data) == 0 {
+		return len(p), nil
+	}
+
+	ev, err := types.EncodeEvent(&types.InputPayload{Data: data})
+	if err != nil {
+		return 0, err
+	}
+	if err := s.send(ev); err != nil {
+		return 0, err
+	}
+	s.record(&types.InputPayload{Data: data})
+	return len(p), nil
+}
+
+func (s *ShellSession) binary() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sock != nil && s.base64
+}
+
+// Read output from the play, io.EOF is returned once the session has ended
+// and all of its output has been read
+func (s *ShellSession) Read(p []byte) (int, error) {
+	s.readMu.Lock()
+	defer s.readMu.Unlock()
+
+	if len(s.pending) == 0 {
+		data, ok := <-s.out
+		if !ok {
+			return 0, io.EOF
+		}
+		s.pending = data
+	}
+
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+// Receives each chunk of output from the play, it is closed once the session
+// has ended. Output must be drained, either here or with Read, as only a
+// bounded backlog is kept for a slow consumer. Rather than lose output the
+// session ends with CloseLocalError and ErrOutputOverflow once it fills up.
+func (s *ShellSession) Output() <-chan []byte {
+	return s.out
+}
+
+// Resize the remote terminal, empty sizes and repeats of the last size are
+// ignored
+func (s *ShellSession) Resize(rows, cols uint16) error {
+	select {
+	case <-s.done:
+		return ErrShellClosed
+	default:
+	}
+	if s.readOnly {
+		return ErrReadOnly
+	}
+
+	if rows != 0 && cols != 0 {
+		s.mu.Lock()
+		changed := rows != s.rows || cols != s.cols
+		s.rows, s.cols = rows, cols
+		s.mu.Unlock()
+		if changed {
+			s.record(&types.ResizePayload{Rows: rows, Cols: cols})
+		}
+	}
+	return s.resize(rows, cols)
+}
+
+// End the session, this is reported as CloseUserExit
+func (s *ShellSession) Close() error {
+	s.close(CloseUserExit, nil)
+	return nil
+}
+
+// Closed once the session has ended
+func (s *ShellSession) Done() <-chan struct{} {
+	return s.done
+}
+
+// Why the session ended, empty until Done is closed
+func (s *ShellSession) Reason() CloseReason {
+	select {
+	case <-s.done:
+		return s.reason
+	default:
+		return ""
+	}
+}
+
+// Nil while the session is running or if it ended cleanly, the context's
+// error when it was cancelled and a *ShellError when something failed
+func (s *ShellSession) Err() error {
+	select {
+	case <-s.done:
+		return s.result()
+	default:
+		return nil
+	}
+}
+
+// End the session with the reason, the first call wins
+func (s *ShellSession) close(reason CloseReason, err error) {
+	closed := false
+	s.once.Do(func() {
+		s.reason, s.err = reason, err
+		close(s.done)
+		if s.cancel != nil {
+			s.cancel()
+		}
+
+		s.mu.Lock()
+		sock := s.sock
+		s.mu.Unlock()
+		if sock != nil {
+			sock.Close()
+		}
+		closed = true
+	})
+	if closed && s.onClose != nil {
+		s.onClose(reason, err)
+	}
+}
+
+func (s *ShellSession) result() error {
+	if s.err == nil {
+		return nil
+	}
+	if s.reason == CloseUserExit {
+		return s.err
+	}
+	return &ShellError{Reason: s.reason, Err: s.err}
+}
+
+// Work out why reading from the socket failed
+func (s *ShellSession) classify(err error) (CloseReason, error) {
+	if s.finished.Load() {
+		return ClosePlayFinished, nil
+	}
+	if errors.Is(err, io.EOF) || websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+		return CloseServer, nil
+	}
+	// Abnormal closure is reported when the connection drops without a close frame
+	var ce *websocket.CloseError
+	if errors.As(err, &ce) && ce.Code != websocket.CloseAbnormalClosure {
+		return CloseServer, err
+	}
+	return CloseNetworkError, err
+}
+
+func (s *ShellSession) readLoop(sock Socket) {
+	for {
+		err := s.readFrom(sock)
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+		if s.keepalive != nil {
+			err = s.deadConnection(err)
+		}
+
+		reason, cerr := s.classify(err)
+		if s.dial == nil || !shouldReconnect(reason, err) {
+			s.close(reason, cerr)
+			return
+		}
+
+		s.detach(sock)
+		var ok bool
+		if sock, ok = s.reconnect(err); !ok {
+			return
+		}
+	}
+}
+
+// Read events until the socket fails or the session ends
+func (s *ShellSession) readFrom(sock Socket) error {
+	for {
+		msg, err := sock.Read()
+		if err != nil {
+			return err
+		}
+		s.heard.Store(int64(time.Since(s.epoch)))
+
+		payload, err := types.DecodeEvent(msg)
+		if err != nil {
+			// The play has still finished even if the server didn't say how
+			if msg.Type == types.PlayFinished {
+				s.playFinished(&types.PlayFinishedPayload{Reason: msg.Content})
+				return nil
+			}
+			continue
+		}
+
+		switch p := payload.(type) {
+		case *types.PingPayload:
+			// A failed write surfaces as a failed read on the same socket
+			if err := s.write(sock, &types.PongPayload{Data: p.Data}); err != nil {
+				sock.Close()
+			}
+		case *types.PongPayload:
+			s.pong([]byte(p.Data))
+		case *types.ActivePlayPayload:
+			s.activePlay(&p.Play)
+		case *types.PlayFinishedPayload:
+			s.playFinished(p)
+			return nil
+		case *types.ResizePayload:
+			s.record(p)
+			if s.onResize != nil {
+				s.onResize(p.Rows, p.Cols)
+			}
+		case *types.OutputPayload:
+			if len(p.Data) == 0 {
+				continue
+			}
+			s.record(p)
+			if !s.queue(p.Data) {
+				s.close(CloseLocalError, ErrOutputOverflow)
+				return nil
+			}
+		}
+	}
+}
+
+// Queue output without blocking so control frames keep being read, returns
+// false when the backlog is full
+func (s *ShellSession) queue(data []byte) bool {
+	s.backlogMu.Lock()
+	defer s.backlogMu.Unlock()
+	if s.queued+len(data) > sessionOutputBacklog {
+		return false
+	}
+	s.backlog = append(s.backlog, data)
+	s.queued += len(data)
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// Move queued output to the Output channel as it has room. Once the session
+// ends whatever still fits is delivered before the channel is closed.
+func (s *ShellSession) deliver() {
+	defer close(s.out)
+	for {
+		s.backlogMu.Lock()
+		if len(s.backlog) == 0 {
+			s.backlogMu.Unlock()
+			select {
+			case <-s.wake:
+				continue
+			case <-s.done:
+				s.flush()
+				return
+			}
+		}
+		data := s.backlog[0]
+		s.backlogMu.Unlock()
+
+		select {
+		case s.out <- data:
+			s.backlogMu.Lock()
+			s.backlog = s.backlog[1:]
+			s.queued -= len(data)
+			s.backlogMu.Unlock()
+		case <-s.done:
+			s.flush()
+			return
+		}
+	}
+}
+
+func (s *ShellSession) flush() {
+	s.backlogMu.Lock()
+	defer s.backlogMu.Unlock()
+	for len(s.backlog) > 0 {
+		select {
+		case s.out <- s.backlog[0]:
+			s.backlog = s.backlog[1:]
+		default:
+			return
+		}
+	}
+}
+
+func (s *ShellSession) record(p types.Payload) {
+	if s.recorder != nil {
+		s.recorder.RecordEvent(time.Now(), p)
+	}
+}
+
+// Encode the payload and write it straight to the socket
+func (s *ShellSession) write(sock Socket, p types.Payload) error {
+	ev, err := types.EncodeEvent(p)
+	if err != nil {
+		return err
+	}
+	return sock.Write(ev)
+}
+
+func (s *ShellSession) activePlay(play *types.Play) {
+	s.play.Store(play)
+	if s.onPlay != nil {
+		s.onPlay(play)
+	}
+}
+
+// End the session cleanly without waiting for the server to close the socket
+func (s *ShellSession) playFinished(p *types.PlayFinishedPayload) {
+	s.finished.Store(true)
+	s.finish.Store(p)
+	if s.onFinish != nil {
+		s.onFinish(p)
+	}
+	s.close(ClosePlayFinished, nil)
+}
+
+// The play as last sent by the server, nil until it has been received
+func (s *ShellSession) Play() *types.Play {
+	return s.play.Load()
+}
+
+// How the play finished, nil unless the session ended with ClosePlayFinished
+// because the server reported it
+func (s *ShellSession) Finished() *types.PlayFinishedPayload {
+	return s.finish.Load()
+}
+
+// Write an event to the current socket, waiting out a reconnect
+func (s *ShellSession) send(msg *types.SocketEvent) error {
+	for {
+		select {
+		case <-s.done:
+			return ErrShellClosed
+		default:
+		}
+
+		s.mu.Lock()
+		sock, base64, ready := s.sock, s.base64, s.ready
+		s.mu.Unlock()
+
+		select {
+		case <-s.done:
+			return ErrShellClosed
+		case <-ready:
+		}
+		if sock == nil {
+			continue
+		}
+
+		out := msg
+		if base64 && msg.Type == types.Input {
+			encoded := *msg
+			encoded.EncodeBase64()
+			out = &encoded
+		}
+		err := sock.Write(out)
+		if err == nil {
+			return nil
+		}
+		if s.dial == nil {
+			s.close(CloseNetworkError, err)
+			return err
+		}
+		// The read loop sees the socket fail and reconnects
+		s.detach(sock)
+		sock.Close()
+	}
+}
+
+// Stop using a socket that has failed, writes wait until attach is called
+func (s *ShellSession) detach(sock Socket) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sock == sock {
+		s.sock = nil
+		s.ready = make(chan struct{})
+	}
+}
+
+// Start using a new socket and restore the terminal size on it, returns false
+// if the session ended while it was being dialed
+func (s *ShellSession) attach(sock Socket, base64 bool) bool {
+	s.mu.Lock()
+	select {
+	case <-s.done:
+		s.mu.Unlock()
+		sock.Close()
+		return false
+	default:
+	}
+	s.prepare(sock)
+	rows, cols := s.rows, s.cols
+	if rows != 0 && cols != 0 {
+		s.write(sock, &types.ResizePayload{Rows: rows, Cols: cols})
+	}
+	s.sock, s.base64 = sock, base64
+	close(s.ready)
+	s.mu.Unlock()
+	return true
+}
+
+// Keep the remote terminal the same size as the local one
+func (s *ShellSession) follow(size TerminalSize) {
+	update := func() {
+		if rows, cols, err := size.Size(); err == nil {
+			s.Resize(rows, cols)
+		}
+	}
+
+	update()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-size.Resized():
+			update()
+		}
+	}
+}
+
+// Returns a func that sends resize events, sizes that are empty or the same
+// as the last one sent are skipped
+func resizer(sock Socket) func(rows, cols uint16) error {
+	mu := &sync.Mutex{}
+	var lastRows, lastCols uint16
+
+	return func(rows, cols uint16) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if rows == 0 || cols == 0 || (rows == lastRows && cols == lastCols) {
+			return nil
+		}
+		ev, err := types.EncodeEvent(&types.ResizePayload{Rows: rows, Cols: cols})
+		if err != nil {
+			return err
+		}
+		if err := sock.Write(ev); err != nil {
+			return err
+		}
+		lastRows, lastCols = rows, cols
+		return nil
+	}
+}
+
+// Lets resizer write through the session's current socket
+type sessionSocket struct {
+	s *ShellSession
+}
+
+func (s sessionSocket) Read() (*types.SocketEvent, error) {
+	return nil, errors.New("not readable")
+}
+
+func (s sessionSocket) Write(msg *types.SocketEvent) error {
+	return s.s.send(msg)
+}
+
+func (s sessionSocket) Close() error {
+	return s.s.Close()
+}
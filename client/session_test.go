@@ -0,0 +1,149 @@
+package client
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srepio/sdk/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenShell(t *testing.T) {
+	tc := prepareShell(t, nil)
+
+	s, err := tc.client.OpenShell(context.Background(), &GetShellRequest{ID: tc.playID, Rows: 24, Cols: 80}, nil)
+	require.Nil(t, err)
+	defer s.Close()
+
+	assert.Eventually(t, func() bool {
+		rows, cols := tc.server.ShellSize(tc.playID)
+		return rows == 24 && cols == 80
+	}, time.Second, time.Millisecond*10)
+
+	require.Nil(t, s.Resize(40, 120))
+	assert.Eventually(t, func() bool {
+		rows, cols := tc.server.ShellSize(tc.playID)
+		return rows == 40 && cols == 120
+	}, time.Second, time.Millisecond*10)
+
+	_, err = s.Write([]byte("whoami\n"))
+	require.Nil(t, err)
+
+	output := ""
+	timeout := time.After(time.Second * 5)
+	for !strings.HasSuffix(output, "whoami\n") {
+		select {
+		case data := <-s.Output():
+			output += string(data)
+		case <-timeout:
+			t.Fatalf("echo not received, got %q", output)
+		}
+	}
+
+	_, err = tc.client.CheckPlay(context.Background(), &CheckPlayRequest{ID: tc.playID})
+	require.Nil(t, err)
+
+	select {
+	case <-s.Done():
+	case <-time.After(time.Second * 5):
+		t.Fatal("session did not finish")
+	}
+	assert.Equal(t, ClosePlayFinished, s.Reason())
+	assert.Nil(t, s.Err())
+
+	_, err = s.Write([]byte("too late"))
+	assert.ErrorIs(t, err, ErrShellClosed)
+	assert.ErrorIs(t, s.Resize(10, 10), ErrShellClosed)
+}
+
+func TestShellSessionRead(t *testing.T) {
+	tc := prepareShell(t, nil)
+
+	s, err := tc.client.OpenShell(context.Background(), &GetShellRequest{ID: tc.playID}, nil)
+	require.Nil(t, err)
+
+	_, err = s.Write([]byte("ls\n"))
+	require.Nil(t, err)
+	s.Close()
+
+	// Whatever arrived before closing can still be read, then io.EOF
+	_, err = io.ReadAll(s)
+	assert.Nil(t, err)
+	assert.Equal(t, CloseUserExit, s.Reason())
+	assert.Nil(t, s.Err())
+}
+
+func TestShellSessionClosesWithItsContext(t *testing.T) {
+	tc := prepareShell(t, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var reason CloseReason
+	closed := make(chan struct{})
+	s, err := tc.client.OpenShell(ctx, &GetShellRequest{ID: tc.playID}, &ShellOptions{
+		OnClose: func(r CloseReason, _ error) {
+			reason = r
+			close(closed)
+		},
+	})
+	require.Nil(t, err)
+
+	cancel()
+	select {
+	case <-closed:
+	case <-time.After(time.Second * 5):
+		t.Fatal("session did not close")
+	}
+	assert.Equal(t, CloseUserExit, reason)
+	assert.ErrorIs(t, s.Err(), context.Canceled)
+}
+
+func TestShellSessionHandlesPlayEvents(t *testing.T) {
+	tc := prepareShell(t, nil)
+
+	plays := make(chan *types.Play, 1)
+	finished := make(chan *types.PlayFinishedPayload, 1)
+	s, err := tc.client.OpenShell(context.Background(), &GetShellRequest{ID: tc.playID}, &ShellOptions{
+		OnActivePlay: func(play *types.Play) {
+			plays <- play
+		},
+		OnPlayFinished: func(ev *types.PlayFinishedPayload) {
+			finished <- ev
+		},
+	})
+	require.Nil(t, err)
+
+	select {
+	case play := <-plays:
+		assert.Equal(t, tc.playID, play.ID)
+		assert.Equal(t, types.PlayRunning, play.Status)
+	case <-time.After(time.Second * 5):
+		t.Fatal("active play not received")
+	}
+	assert.Equal(t, tc.playID, s.Play().ID)
+
+	_, err = s.Write([]byte("ls\n"))
+	require.Nil(t, err)
+	readUntil(t, s, "ls\n")
+	_, err = tc.client.CheckPlay(context.Background(), &CheckPlayRequest{ID: tc.playID})
+	require.Nil(t, err)
+
+	select {
+	case ev := <-finished:
+		assert.Equal(t, types.PlayCompleted, ev.Status)
+		assert.Equal(t, "check passed", ev.Reason)
+	case <-time.After(time.Second * 5):
+		t.Fatal("play finished not received")
+	}
+	<-s.Done()
+	assert.Equal(t, ClosePlayFinished, s.Reason())
+	assert.Equal(t, types.PlayCompleted, s.Finished().Status)
+
+	// The control events are not part of the output
+	output, err := io.ReadAll(s)
+	require.Nil(t, err)
+	assert.Empty(t, output)
+}
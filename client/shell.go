@@ -0,0 +1,218 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/srepio/sdk/types"
+)
+
+type ShellOptions struct {
+	// Reports the size of the local terminal, after the initial size from the
+	// request the remote size is left alone when nil
+	Size TerminalSize
+	// Switch stdin to raw mode for the session when it is a terminal, it is
+	// restored on return, panic, context cancellation and signals
+	RawMode bool
+	// Called once the session has ended with the reason it ended, err is nil
+	// when it ended cleanly
+	OnClose func(reason CloseReason, err error)
+	// Called with the play the server sends on connecting, including after
+	// reconnecting
+	OnActivePlay func(play *types.Play)
+	// Called when the server reports the play has finished, the session then
+	// ends with ClosePlayFinished
+	OnPlayFinished func(ev *types.PlayFinishedPayload)
+	// Reconnect when the connection drops unexpectedly and wait for the play
+	// to boot instead of returning ErrTooEarly, disabled when nil
+	Reconnect *ReconnectOptions
+	// Send heartbeats and treat the connection as dead when they go
+	// unanswered, disabled when nil
+	Keepalive *KeepaliveOptions
+	// Receives the input, output and resizes of the session
+	Recorder ShellRecorder
+	// Called when the server resizes the terminal, which it does for
+	// spectators when the player resizes theirs
+	OnResize func(rows, cols uint16)
+}
+
+// Receives a copy of what happens on a shell session as it happens, the
+// payload is one of *types.InputPayload, *types.OutputPayload or
+// *types.ResizePayload, and must not be kept after the call returns. Calls
+// may come from more than one goroutine. See the asciicast package for a
+// recorder that writes asciinema files.
+type ShellRecorder interface {
+	RecordEvent(at time.Time, p types.Payload)
+}
+
+// Open a shell for the play, stdin is sent to the play and its output is
+// written to stdout until the session ends. Nil is returned when the session
+// ends cleanly, the context's error when it is cancelled and a *ShellError
+// when something fails.
+func (c *Client) GetShell(ctx context.Context, req *GetShellRequest, stdin io.Reader, stdout io.Writer, opts *ShellOptions) error {
+	if opts == nil {
+		opts = &ShellOptions{}
+	}
+
+	// OnClose is left until the terminal has been restored
+	sopts := *opts
+	sopts.OnClose = nil
+	s, err := c.OpenShell(ctx, req, &sopts)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	raw := &rawTerminal{once: &sync.Once{}}
+	if f, ok := stdin.(*os.File); ok && opts.RawMode {
+		raw, err = makeRaw(ctx, f)
+		if err != nil {
+			return err
+		}
+	}
+	defer raw.Restore()
+
+	output := make(chan struct{})
+	go func() {
+		defer close(output)
+		defer raw.restoreOnPanic()
+		copyOutput(s, stdout)
+	}()
+
+	in, interruptible := cancelable(stdin)
+	input := make(chan struct{})
+	go func() {
+		defer close(input)
+		defer raw.restoreOnPanic()
+		copyInput(s, in)
+	}()
+
+	<-s.Done()
+	in.Cancel()
+	<-output
+	if interruptible {
+		<-input
+		in.Close()
+	}
+	raw.Restore()
+
+	if opts.OnClose != nil {
+		opts.OnClose(s.reason, s.err)
+	}
+	return s.result()
+}
+
+// Dial the play's shell, base64 reports whether the server agreed to base64
+// encoded events
+func (c *Client) dialShell(ctx context.Context, req *GetShellRequest) (sock Socket, base64 bool, err error) {
+	if err := req.Validate(); err != nil {
+		return nil, false, err
+	}
+	headers := make(http.Header)
+	headers.Add("Sec-Websocket-Protocol", types.Base64Protocol)
+
+	var params url.Values
+	if req.Terminal != "" {
+		params = url.Values{"terminal": {req.Terminal}}
+	}
+	sock, resp, err := c.dialSocket(ctx, fmt.Sprintf("/plays/%s/shell", req.ID), params, headers)
+	if err != nil {
+		return nil, false, err
+	}
+	return sock, negotiatedBase64(resp), nil
+}
+
+// Open an authenticated websocket to the API
+func (c *Client) dialSocket(ctx context.Context, path string, params url.Values, headers http.Header) (Socket, *http.Response, error) {
+	var scheme string
+	if c.Options.Scheme == "https" {
+		scheme = "wss"
+	} else {
+		scheme = "ws"
+	}
+
+	url := url.URL{
+		Scheme:   scheme,
+		Host:     c.Options.Url,
+		Path:     path,
+		RawQuery: params.Encode(),
+	}
+	if headers == nil {
+		headers = make(http.Header)
+	}
+	headers.Add("Authorization", fmt.Sprintf("Bearer %s", c.Options.Token))
+
+	if c.dump != nil {
+		c.dump.request(http.MethodGet, url.String(), headers, nil)
+	}
+	sock, resp, err := c.dialer.DialSocket(ctx, url.String(), headers)
+	if c.dump != nil {
+		if resp != nil {
+			c.dump.response(resp, nil)
+		} else if err != nil {
+			c.dump.error(http.MethodGet, url.String(), err)
+		}
+	}
+	if err != nil {
+		if resp == nil {
+			return nil, nil, err
+		}
+		if resp.StatusCode == http.StatusTooEarly {
+			return nil, nil, ErrTooEarly
+		}
+		return nil, nil, &handshakeError{err: err, status: resp.StatusCode}
+	}
+
+	return sock, resp, nil
+}
+
+func copyOutput(s *ShellSession, stdout io.Writer) {
+	for data := range s.Output() {
+		if _, err := stdout.Write(data); err != nil {
+			s.close(CloseLocalError, err)
+			return
+		}
+	}
+}
+
+func copyInput(s *ShellSession, stdin io.Reader) {
+	buffer := make([]byte, 1024)
+	for {
+		n, err := stdin.Read(buffer)
+		if n > 0 {
+			if _, werr := s.Write(buffer[:n]); werr != nil {
+				return
+			}
+		}
+		if err != nil {
+			switch {
+			case errors.Is(err, errReadCancelled):
+			case errors.Is(err, io.EOF):
+				s.close(CloseUserExit, nil)
+			default:
+				s.close(CloseLocalError, err)
+			}
+			return
+		}
+	}
+}
+
+// Open a shell for the play using the local terminal, the remote terminal is
+// kept the same size as stdout when it is a terminal
+func (c *Client) GetTerminalShell(ctx context.Context, req *GetShellRequest, stdin *os.File, stdout *os.File) error {
+	opts := &ShellOptions{}
+	if IsTerminal(stdout) {
+		size := NewFileSize(stdout)
+		defer size.Stop()
+		opts.Size = size
+	}
+
+	return c.GetShell(ctx, req, stdin, stdout, opts)
+}
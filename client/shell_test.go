@@ -0,0 +1,329 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/srepio/sdk/srepfake"
+	"github.com/srepio/sdk/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSize struct {
+	mu      *sync.Mutex
+	rows    uint16
+	cols    uint16
+	resized chan struct{}
+}
+
+func newFakeSize(rows, cols uint16) *fakeSize {
+	return &fakeSize{
+		mu:      &sync.Mutex{},
+		rows:    rows,
+		cols:    cols,
+		resized: make(chan struct{}, 1),
+	}
+}
+
+func (s *fakeSize) Size() (uint16, uint16, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rows, s.cols, nil
+}
+
+func (s *fakeSize) Resized() <-chan struct{} {
+	return s.resized
+}
+
+func (s *fakeSize) resize(rows, cols uint16) {
+	s.mu.Lock()
+	s.rows, s.cols = rows, cols
+	s.mu.Unlock()
+	s.resized <- struct{}{}
+}
+
+type syncBuffer struct {
+	mu  *sync.Mutex
+	buf *bytes.Buffer
+}
+
+func newSyncBuffer() *syncBuffer {
+	return &syncBuffer{mu: &sync.Mutex{}, buf: &bytes.Buffer{}}
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+type shellTestCase struct {
+	server *srepfake.Server
+	client *Client
+	playID string
+}
+
+func prepareShell(t *testing.T, opts *srepfake.Options) *shellTestCase {
+	s := srepfake.New(opts)
+	t.Cleanup(s.Close)
+	_, token := s.NewUser("Bongo", "bongo@srep.io", "hunter2hunter2")
+
+	c := NewClient(&ClientOptions{
+		Url:    s.Host(),
+		Scheme: "http",
+		Token:  token,
+	})
+	started, err := c.StartPlay(context.Background(), &StartPlayRequest{Scenario: "mango"})
+	require.Nil(t, err)
+
+	return &shellTestCase{server: s, client: c, playID: started.Play.ID}
+}
+
+func TestGetShellWithReaderAndWriter(t *testing.T) {
+	tc := prepareShell(t, nil)
+	size := newFakeSize(24, 80)
+	stdin, input := io.Pipe()
+	defer input.Close()
+	stdout := newSyncBuffer()
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- tc.client.GetShell(context.Background(), &GetShellRequest{ID: tc.playID}, stdin, stdout, &ShellOptions{
+			Size: size,
+		})
+	}()
+
+	assert.Eventually(t, func() bool {
+		rows, cols := tc.server.ShellSize(tc.playID)
+		return rows == 24 && cols == 80
+	}, time.Second, time.Millisecond*10)
+
+	size.resize(40, 120)
+	assert.Eventually(t, func() bool {
+		rows, cols := tc.server.ShellSize(tc.playID)
+		return rows == 40 && cols == 120
+	}, time.Second, time.Millisecond*10)
+
+	input.Write([]byte("whoami\n"))
+	assert.Eventually(t, func() bool {
+		return strings.HasSuffix(stdout.String(), "whoami\n")
+	}, time.Second, time.Millisecond*10)
+
+	_, err := tc.client.CancelPlay(context.Background(), &CancelPlayRequest{ID: tc.playID})
+	require.Nil(t, err)
+	select {
+	case err := <-errs:
+		assert.Nil(t, err)
+	case <-time.After(time.Second * 5):
+		t.Fatal("shell did not finish")
+	}
+}
+
+func TestGetShellSendsTheRequestedSize(t *testing.T) {
+	tc := prepareShell(t, nil)
+	stdin, input := io.Pipe()
+	defer input.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tc.client.GetShell(ctx, &GetShellRequest{ID: tc.playID, Rows: 30, Cols: 100}, stdin, io.Discard, nil)
+
+	assert.Eventually(t, func() bool {
+		rows, cols := tc.server.ShellSize(tc.playID)
+		return rows == 30 && cols == 100
+	}, time.Second, time.Millisecond*10)
+}
+
+type recordingSocket struct {
+	mu     *sync.Mutex
+	events []*types.SocketEvent
+}
+
+func (s *recordingSocket) Read() (*types.SocketEvent, error) {
+	return nil, io.EOF
+}
+
+func (s *recordingSocket) Write(msg *types.SocketEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, msg)
+	return nil
+}
+
+func (s *recordingSocket) Close() error {
+	return nil
+}
+
+func TestResizerSkipsDuplicateAndEmptySizes(t *testing.T) {
+	sock := &recordingSocket{mu: &sync.Mutex{}}
+	resize := resizer(sock)
+
+	resize(0, 0)
+	resize(24, 80)
+	resize(24, 80)
+	resize(25, 80)
+
+	require.Len(t, sock.events, 2)
+	assert.Equal(t, "24,80", sock.events[0].Content)
+	assert.Equal(t, "25,80", sock.events[1].Content)
+}
+
+// A socket whose writes always fail
+type brokenSocket struct{}
+
+func (brokenSocket) Read() (*types.SocketEvent, error) {
+	return nil, io.EOF
+}
+
+func (brokenSocket) Write(msg *types.SocketEvent) error {
+	return io.ErrClosedPipe
+}
+
+func (brokenSocket) Close() error {
+	return nil
+}
+
+func TestResizerReturnsWriteErrors(t *testing.T) {
+	resize := resizer(brokenSocket{})
+
+	assert.ErrorIs(t, resize(24, 80), io.ErrClosedPipe)
+	// A size that failed to send is not skipped as a repeat
+	assert.ErrorIs(t, resize(24, 80), io.ErrClosedPipe)
+	assert.Nil(t, resize(0, 0))
+}
+
+func TestGetShellLifecycle(t *testing.T) {
+	type testCase struct {
+		name   string
+		end    func(t *testing.T, tc *shellTestCase, input *os.File, cancel context.CancelFunc)
+		reason CloseReason
+		check  func(t *testing.T, err error)
+	}
+
+	cases := []testCase{
+		{
+			name: "play_finished",
+			end: func(t *testing.T, tc *shellTestCase, _ *os.File, _ context.CancelFunc) {
+				_, err := tc.client.CheckPlay(context.Background(), &CheckPlayRequest{ID: tc.playID})
+				require.Nil(t, err)
+			},
+			reason: ClosePlayFinished,
+			check: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name: "stdin_closed",
+			end: func(t *testing.T, _ *shellTestCase, input *os.File, _ context.CancelFunc) {
+				input.Close()
+			},
+			reason: CloseUserExit,
+			check: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name: "context_cancelled",
+			end: func(t *testing.T, _ *shellTestCase, _ *os.File, cancel context.CancelFunc) {
+				cancel()
+			},
+			reason: CloseUserExit,
+			check: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, context.Canceled)
+			},
+		},
+		{
+			name: "connection_dropped",
+			end: func(t *testing.T, tc *shellTestCase, _ *os.File, _ context.CancelFunc) {
+				tc.server.DropConnections(tc.playID)
+			},
+			reason: CloseNetworkError,
+			check: func(t *testing.T, err error) {
+				var serr *ShellError
+				require.ErrorAs(t, err, &serr)
+				assert.Equal(t, CloseNetworkError, serr.Reason)
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tc := prepareShell(t, nil)
+			stdin, input, err := os.Pipe()
+			require.Nil(t, err)
+			defer stdin.Close()
+			defer input.Close()
+			stdout := newSyncBuffer()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var reason CloseReason
+			connected := make(chan struct{})
+			errs := make(chan error, 1)
+			go func() {
+				errs <- tc.client.GetShell(ctx, &GetShellRequest{ID: tc.playID}, stdin, stdout, &ShellOptions{
+					OnActivePlay: func(*types.Play) {
+						close(connected)
+					},
+					OnClose: func(r CloseReason, _ error) {
+						reason = r
+					},
+				})
+			}()
+
+			select {
+			case <-connected:
+			case <-time.After(time.Second * 5):
+				t.Fatal("shell did not connect")
+			}
+			c.end(t, tc, input, cancel)
+
+			select {
+			case err := <-errs:
+				c.check(t, err)
+				assert.Equal(t, c.reason, reason)
+			case <-time.After(time.Second * 5):
+				t.Fatal("shell did not finish")
+			}
+		})
+	}
+}
+
+func TestGetShellStopsReadingStdin(t *testing.T) {
+	tc := prepareShell(t, nil)
+	stdin, input, err := os.Pipe()
+	require.Nil(t, err)
+	defer stdin.Close()
+	defer input.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		errs <- tc.client.GetShell(ctx, &GetShellRequest{ID: tc.playID}, stdin, io.Discard, nil)
+	}()
+	time.Sleep(time.Millisecond * 50)
+	cancel()
+	<-errs
+
+	// Nothing is left reading stdin, so the input is still there for us
+	input.Write([]byte("x"))
+	buf := make([]byte, 1)
+	stdin.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := stdin.Read(buf)
+	require.Nil(t, err)
+	assert.Equal(t, "x", string(buf[:n]))
+}
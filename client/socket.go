@@ -1,16 +1,67 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
+	"net/http"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/srepio/sdk/types"
 )
 
+// A connection that carries socket events, such as a play shell
+type Socket interface {
+	Read() (*types.SocketEvent, error)
+	Write(msg *types.SocketEvent) error
+	Close() error
+}
+
+// Implemented by sockets that can fail reads and writes which block for too
+// long, zero disables the timeout
+type timeoutSocket interface {
+	SetTimeouts(read, write time.Duration)
+}
+
+// Implemented by sockets with transport level pings, such as websocket
+// control frames
+type pingSocket interface {
+	Ping(payload []byte) error
+	SetPongHandler(h func(payload []byte))
+}
+
+// Opens sockets, implementations can be set on ClientOptions to intercept
+// the websocket connections made by the client
+type SocketDialer interface {
+	DialSocket(ctx context.Context, url string, header http.Header) (Socket, *http.Response, error)
+}
+
+type websocketDialer struct {
+	dialer *websocket.Dialer
+}
+
+// A SocketDialer that opens real websocket connections
+func NewWebsocketDialer(dialer *websocket.Dialer) SocketDialer {
+	if dialer == nil {
+		dialer = websocket.DefaultDialer
+	}
+	return &websocketDialer{dialer: dialer}
+}
+
+func (d *websocketDialer) DialSocket(ctx context.Context, url string, header http.Header) (Socket, *http.Response, error) {
+	conn, resp, err := d.dialer.DialContext(ctx, url, header)
+	if err != nil {
+		return nil, resp, err
+	}
+	return newWs(conn), resp, nil
+}
+
 type ws struct {
-	conn *websocket.Conn
-	mu   *sync.Mutex
+	conn         *websocket.Conn
+	mu           *sync.Mutex
+	readTimeout  time.Duration
+	writeTimeout time.Duration
 }
 
 func newWs(conn *websocket.Conn) *ws {
@@ -21,6 +72,9 @@ func newWs(conn *websocket.Conn) *ws {
 }
 
 func (ws *ws) Read() (*types.SocketEvent, error) {
+	if ws.readTimeout > 0 {
+		ws.conn.SetReadDeadline(time.Now().Add(ws.readTimeout))
+	}
 	_, raw, err := ws.conn.ReadMessage()
 	if err != nil {
 		return nil, err
@@ -36,5 +90,43 @@ func (ws *ws) Write(msg *types.SocketEvent) error {
 	ws.mu.Lock()
 	defer ws.mu.Unlock()
 
+	if ws.writeTimeout > 0 {
+		ws.conn.SetWriteDeadline(time.Now().Add(ws.writeTimeout))
+	}
 	return ws.conn.WriteJSON(msg)
 }
+
+// Must be called before the socket is read from
+func (ws *ws) SetTimeouts(read, write time.Duration) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	ws.readTimeout, ws.writeTimeout = read, write
+}
+
+func (ws *ws) Ping(payload []byte) error {
+	ws.mu.Lock()
+	timeout := ws.writeTimeout
+	ws.mu.Unlock()
+	if timeout <= 0 {
+		timeout = defaultKeepaliveWriteTimeout
+	}
+
+	return ws.conn.WriteControl(websocket.PingMessage, payload, time.Now().Add(timeout))
+}
+
+// Must be called before the socket is read from, receiving a pong extends
+// the read deadline
+func (ws *ws) SetPongHandler(h func(payload []byte)) {
+	ws.conn.SetPongHandler(func(data string) error {
+		if ws.readTimeout > 0 {
+			ws.conn.SetReadDeadline(time.Now().Add(ws.readTimeout))
+		}
+		h([]byte(data))
+		return nil
+	})
+}
+
+func (ws *ws) Close() error {
+	return ws.conn.Close()
+}
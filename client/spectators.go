@@ -0,0 +1,145 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/srepio/sdk/types"
+)
+
+type GetSpectatorsRequest struct {
+	ID string `json:"id" param:"id"`
+}
+
+func (r GetSpectatorsRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.ID, validation.Required, validation.Match(regexp.MustCompile(uuidRegex))),
+	)
+}
+
+type GetSpectatorsResponse struct {
+	Spectators []*types.User `json:"spectators"`
+}
+
+// List the users allowed to watch a play
+func (c *Client) GetSpectators(ctx context.Context, req *GetSpectatorsRequest) (*GetSpectatorsResponse, error) {
+	hreq, err := c.buildRequest(http.MethodGet, fmt.Sprintf("/plays/%s/spectators", req.ID), req, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return do[GetSpectatorsResponse](ctx, c.hc, hreq)
+}
+
+type AddSpectatorRequest struct {
+	ID    string `json:"id" param:"id"`
+	Email string `json:"email"`
+}
+
+func (r AddSpectatorRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.ID, validation.Required, validation.Match(regexp.MustCompile(uuidRegex))),
+		validation.Field(&r.Email, validation.Required, validation.NewStringRule(isValidEmail, "must be a valid email address")),
+	)
+}
+
+type AddSpectatorResponse struct {
+	Spectator *types.User `json:"spectator"`
+}
+
+// Allow another user to watch a play's shell with WatchShell
+func (c *Client) AddSpectator(ctx context.Context, req *AddSpectatorRequest) (*AddSpectatorResponse, error) {
+	hreq, err := c.buildRequest(http.MethodPost, fmt.Sprintf("/plays/%s/spectators", req.ID), req, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return do[AddSpectatorResponse](ctx, c.hc, hreq)
+}
+
+type RemoveSpectatorRequest struct {
+	ID    string `json:"id" param:"id"`
+	Email string `json:"email"`
+}
+
+func (r RemoveSpectatorRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.ID, validation.Required, validation.Match(regexp.MustCompile(uuidRegex))),
+		validation.Field(&r.Email, validation.Required, validation.NewStringRule(isValidEmail, "must be a valid email address")),
+	)
+}
+
+type RemoveSpectatorResponse struct{}
+
+// Stop a user watching a play, any shells they are watching are closed
+func (c *Client) RemoveSpectator(ctx context.Context, req *RemoveSpectatorRequest) (*RemoveSpectatorResponse, error) {
+	hreq, err := c.buildRequest(http.MethodDelete, fmt.Sprintf("/plays/%s/spectators", req.ID), req, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return do[RemoveSpectatorResponse](ctx, c.hc, hreq)
+}
+
+type WatchShellRequest struct {
+	ID string `json:"id" param:"id"`
+	// Defaults to types.DefaultTerminal
+	Terminal string `json:"terminal,omitempty"`
+}
+
+func (r WatchShellRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.ID, validation.Required, validation.Match(regexp.MustCompile(uuidRegex))),
+		validation.Field(&r.Terminal, validation.Match(regexp.MustCompile(terminalRegex))),
+	)
+}
+
+// Watch a play's shell as a spectator, for the play's owner or a user they
+// have added with AddSpectator. The session receives the shell's output and
+// follows the player's terminal size through OnResize. Write and Resize
+// return ErrReadOnly, opts.Size and RawMode are ignored.
+func (c *Client) WatchShell(ctx context.Context, req *WatchShellRequest, opts *ShellOptions) (*ShellSession, error) {
+	if opts == nil {
+		opts = &ShellOptions{}
+	}
+
+	dial := func(ctx context.Context) (Socket, bool, error) {
+		return c.dialWatch(ctx, req)
+	}
+	sock, base64, err := c.connectShell(ctx, dial, opts.Reconnect)
+	if err != nil {
+		return nil, err
+	}
+
+	s := newShellSession(sock, opts)
+	s.base64 = base64
+	s.readOnly = true
+	if opts.Reconnect != nil {
+		s.retry = opts.Reconnect
+		s.dial = dial
+	}
+	s.start(ctx)
+	return s, nil
+}
+
+func (c *Client) dialWatch(ctx context.Context, req *WatchShellRequest) (Socket, bool, error) {
+	if err := req.Validate(); err != nil {
+		return nil, false, err
+	}
+	headers := make(http.Header)
+	headers.Add("Sec-Websocket-Protocol", types.Base64Protocol)
+
+	var params url.Values
+	if req.Terminal != "" {
+		params = url.Values{"terminal": {req.Terminal}}
+	}
+	sock, resp, err := c.dialSocket(ctx, fmt.Sprintf("/plays/%s/watch", req.ID), params, headers)
+	if err != nil {
+		return nil, false, err
+	}
+	return sock, negotiatedBase64(resp), nil
+}
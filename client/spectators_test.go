@@ -0,0 +1,233 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type size struct {
+	rows, cols uint16
+}
+
+func TestWatchShell(t *testing.T) {
+	tc := prepareShell(t, nil)
+	ctx := context.Background()
+	spectator := func(name, email string) *Client {
+		_, token := tc.server.NewUser(name, email, "hunter2hunter2")
+		return NewClient(&ClientOptions{Url: tc.server.Host(), Scheme: "http", Token: token})
+	}
+	teacher := spectator("Teacher", "teacher@srep.io")
+	assistant := spectator("Assistant", "assistant@srep.io")
+
+	_, err := teacher.WatchShell(ctx, &WatchShellRequest{ID: tc.playID}, nil)
+	require.NotNil(t, err, "spectators must be added by the owner")
+
+	for _, email := range []string{"teacher@srep.io", "assistant@srep.io"} {
+		_, err = tc.client.AddSpectator(ctx, &AddSpectatorRequest{ID: tc.playID, Email: email})
+		require.Nil(t, err)
+	}
+	listed, err := tc.client.GetSpectators(ctx, &GetSpectatorsRequest{ID: tc.playID})
+	require.Nil(t, err)
+	require.Len(t, listed.Spectators, 2)
+	assert.Equal(t, "assistant@srep.io", listed.Spectators[0].Email)
+
+	player, err := tc.client.OpenShell(ctx, &GetShellRequest{ID: tc.playID, Rows: 24, Cols: 80}, nil)
+	require.Nil(t, err)
+	defer player.Close()
+	assert.Eventually(t, func() bool {
+		rows, cols := tc.server.ShellSize(tc.playID)
+		return rows == 24 && cols == 80
+	}, time.Second, time.Millisecond*10)
+
+	watch := func(c *Client) (*ShellSession, chan size) {
+		sizes := make(chan size, 8)
+		s, err := c.WatchShell(ctx, &WatchShellRequest{ID: tc.playID}, &ShellOptions{
+			OnResize: func(rows, cols uint16) {
+				sizes <- size{rows, cols}
+			},
+		})
+		require.Nil(t, err)
+		t.Cleanup(func() { s.Close() })
+		return s, sizes
+	}
+	expectSize := func(sizes chan size, want size) {
+		t.Helper()
+		select {
+		case got := <-sizes:
+			assert.Equal(t, want, got)
+		case <-time.After(time.Second * 5):
+			t.Fatalf("resize to %v not received", want)
+		}
+	}
+	teaching, teacherSizes := watch(teacher)
+	assisting, assistantSizes := watch(assistant)
+
+	// Spectators start at the player's size
+	expectSize(teacherSizes, size{24, 80})
+	expectSize(assistantSizes, size{24, 80})
+
+	resp, err := tc.client.GetTerminals(ctx, &GetTerminalsRequest{ID: tc.playID})
+	require.Nil(t, err)
+	require.Len(t, resp.Terminals, 1)
+	assert.Equal(t, 2, resp.Terminals[0].Spectators)
+	assert.Equal(t, 1, resp.Terminals[0].Attached)
+
+	_, err = player.Write([]byte("ls\n"))
+	require.Nil(t, err)
+	readUntil(t, player, "ls\n")
+	readUntil(t, teaching, "ls\n")
+	readUntil(t, assisting, "ls\n")
+
+	require.Nil(t, player.Resize(40, 120))
+	expectSize(teacherSizes, size{40, 120})
+	expectSize(assistantSizes, size{40, 120})
+
+	_, err = teaching.Write([]byte("rm -rf /\n"))
+	assert.ErrorIs(t, err, ErrReadOnly)
+	assert.ErrorIs(t, teaching.Resize(10, 10), ErrReadOnly)
+	expectSilence(t, player)
+
+	_, err = tc.client.RemoveSpectator(ctx, &RemoveSpectatorRequest{ID: tc.playID, Email: "assistant@srep.io"})
+	require.Nil(t, err)
+	waitDone(t, assisting)
+	assert.Equal(t, CloseServer, assisting.Reason())
+
+	_, err = tc.client.CheckPlay(ctx, &CheckPlayRequest{ID: tc.playID})
+	require.Nil(t, err)
+	waitDone(t, teaching)
+	assert.Equal(t, ClosePlayFinished, teaching.Reason())
+}
+
+func TestAddSpectatorRequestValidation(t *testing.T) {
+	type testCase struct {
+		request AddSpectatorRequest
+		passes  bool
+	}
+
+	cases := []testCase{
+		{
+			request: AddSpectatorRequest{
+				ID:    uuid.NewString(),
+				Email: "teacher@srep.io",
+			},
+			passes: true,
+		},
+		{
+			request: AddSpectatorRequest{
+				ID: uuid.NewString(),
+			},
+			passes: false,
+		},
+		{
+			request: AddSpectatorRequest{
+				ID:    uuid.NewString(),
+				Email: "teacher",
+			},
+			passes: false,
+		},
+		{
+			request: AddSpectatorRequest{
+				Email: "teacher@srep.io",
+			},
+			passes: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("add_spectator_validation_%s_%t", c.request.Email, c.passes), func(t *testing.T) {
+			err := c.request.Validate()
+			if c.passes {
+				assert.Nil(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestRemoveSpectatorRequestValidation(t *testing.T) {
+	type testCase struct {
+		request RemoveSpectatorRequest
+		passes  bool
+	}
+
+	cases := []testCase{
+		{
+			request: RemoveSpectatorRequest{
+				ID:    uuid.NewString(),
+				Email: "teacher@srep.io",
+			},
+			passes: true,
+		},
+		{
+			request: RemoveSpectatorRequest{
+				Email: "teacher@srep.io",
+			},
+			passes: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("remove_spectator_validation_%s_%t", c.request.Email, c.passes), func(t *testing.T) {
+			err := c.request.Validate()
+			if c.passes {
+				assert.Nil(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestWatchShellRequestValidation(t *testing.T) {
+	type testCase struct {
+		request WatchShellRequest
+		passes  bool
+	}
+
+	cases := []testCase{
+		{
+			request: WatchShellRequest{
+				ID: uuid.NewString(),
+			},
+			passes: true,
+		},
+		{
+			request: WatchShellRequest{
+				ID:       uuid.NewString(),
+				Terminal: "logs",
+			},
+			passes: true,
+		},
+		{
+			request: WatchShellRequest{
+				ID:       uuid.NewString(),
+				Terminal: "../logs",
+			},
+			passes: false,
+		},
+		{
+			request: WatchShellRequest{
+				ID: "bongo",
+			},
+			passes: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("watch_shell_validation_%s_%t", c.request.Terminal, c.passes), func(t *testing.T) {
+			err := c.request.Validate()
+			if c.passes {
+				assert.Nil(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+var (
+	errReadCancelled = errors.New("read cancelled")
+)
+
+// A reader whose blocked reads can be cancelled, after Cancel reads return
+// errReadCancelled without consuming any input
+type cancelableReader interface {
+	io.Reader
+	Cancel()
+	Close() error
+}
+
+// Wrap the reader so blocked reads can be cancelled, returns false when the
+// reader doesn't support it, in which case a blocked read only returns once
+// the underlying reader does
+func cancelable(r io.Reader) (cancelableReader, bool) {
+	if f, ok := r.(*os.File); ok {
+		if cr, ok := fileReader(f); ok {
+			return cr, true
+		}
+	}
+	if dr, ok := r.(deadliner); ok && dr.SetReadDeadline(time.Time{}) == nil {
+		return &deadlineReader{r: dr}, true
+	}
+	return &plainReader{r: r}, false
+}
+
+type deadliner interface {
+	io.Reader
+	SetReadDeadline(t time.Time) error
+}
+
+type deadlineReader struct {
+	r deadliner
+}
+
+func (d *deadlineReader) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return n, errReadCancelled
+	}
+	return n, err
+}
+
+func (d *deadlineReader) Cancel() {
+	d.r.SetReadDeadline(time.Unix(1, 0))
+}
+
+// Clears the deadline so the reader can be used again
+func (d *deadlineReader) Close() error {
+	return d.r.SetReadDeadline(time.Time{})
+}
+
+type plainReader struct {
+	r io.Reader
+}
+
+func (p *plainReader) Read(b []byte) (int, error) {
+	return p.r.Read(b)
+}
+
+func (p *plainReader) Cancel() {}
+
+func (p *plainReader) Close() error {
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build !unix && !windows
+
+package client
+
+import "os"
+
+// There is no poll to wait on, so file reads fall back to deadlines where the
+// file supports them and plain blocking reads otherwise
+func fileReader(f *os.File) (cancelableReader, bool) {
+	return nil, false
+}
@@ -0,0 +1,80 @@
+//go:build unix
+
+package client
+
+import (
+	"os"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Waits for the file to be readable with poll, alongside a pipe that is
+// written to on cancel. This leaves the caller's file untouched, unlike
+// setting deadlines or non-blocking mode on it.
+type pollReader struct {
+	f    *os.File
+	raw  syscall.RawConn
+	wake [2]int
+	once *sync.Once
+}
+
+func fileReader(f *os.File) (cancelableReader, bool) {
+	raw, err := f.SyscallConn()
+	if err != nil {
+		return nil, false
+	}
+	r := &pollReader{
+		f:    f,
+		raw:  raw,
+		once: &sync.Once{},
+	}
+	// Pipe2 is missing on darwin, so close on exec is set separately
+	if err := unix.Pipe(r.wake[:]); err != nil {
+		return nil, false
+	}
+	unix.CloseOnExec(r.wake[0])
+	unix.CloseOnExec(r.wake[1])
+	return r, true
+}
+
+func (r *pollReader) Read(p []byte) (int, error) {
+	var cancelled bool
+	var perr error
+	err := r.raw.Control(func(fd uintptr) {
+		fds := []unix.PollFd{
+			{Fd: int32(fd), Events: unix.POLLIN},
+			{Fd: int32(r.wake[0]), Events: unix.POLLIN},
+		}
+		for {
+			_, perr = unix.Poll(fds, -1)
+			if perr != unix.EINTR {
+				break
+			}
+		}
+		cancelled = fds[1].Revents != 0
+	})
+	if err != nil {
+		return 0, err
+	}
+	if perr != nil {
+		return 0, perr
+	}
+	if cancelled {
+		return 0, errReadCancelled
+	}
+	return r.f.Read(p)
+}
+
+func (r *pollReader) Cancel() {
+	r.once.Do(func() {
+		unix.Write(r.wake[1], []byte{0})
+	})
+}
+
+// Release the wake up pipe, must only be called once reads have stopped
+func (r *pollReader) Close() error {
+	unix.Close(r.wake[0])
+	return unix.Close(r.wake[1])
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package client
+
+import "os"
+
+// Console handles can't be polled alongside a wake up handle, so file reads
+// fall back to deadlines where the file supports them
+func fileReader(f *os.File) (cancelableReader, bool) {
+	return nil, false
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+var (
+	// Bursts of resize events within this window are sent as one
+	resizeDebounce = time.Millisecond * 50
+	// How often the size is checked on platforms without SIGWINCH
+	resizePollInterval = time.Second
+)
+
+// Reports the size of a local terminal and when it changes
+type TerminalSize interface {
+	Size() (rows, cols uint16, err error)
+	// Receives whenever the terminal has been resized
+	Resized() <-chan struct{}
+}
+
+// The size of the terminal attached to a file, Stop should be called once
+// finished with. Resizes are picked up from SIGWINCH where it is supported.
+type FileSize struct {
+	f       *os.File
+	resized chan struct{}
+	stop    chan struct{}
+	once    *sync.Once
+}
+
+func NewFileSize(f *os.File) *FileSize {
+	s := &FileSize{
+		f:       f,
+		resized: make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		once:    &sync.Once{},
+	}
+	go s.watch()
+	return s
+}
+
+// Whether the file is attached to a terminal
+func IsTerminal(f *os.File) bool {
+	return terminal.IsTerminal(int(f.Fd()))
+}
+
+func (s *FileSize) Size() (uint16, uint16, error) {
+	cols, rows, err := terminal.GetSize(int(s.f.Fd()))
+	if err != nil {
+		return 0, 0, err
+	}
+	return uint16(rows), uint16(cols), nil
+}
+
+func (s *FileSize) Resized() <-chan struct{} {
+	return s.resized
+}
+
+func (s *FileSize) Stop() {
+	s.once.Do(func() {
+		close(s.stop)
+	})
+}
+
+func (s *FileSize) watch() {
+	sigs := make(chan os.Signal, 1)
+	defer signal.Stop(sigs)
+
+	var poll <-chan time.Time
+	if !notifyResize(sigs) {
+		ticker := time.NewTicker(resizePollInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
+	debounce := time.NewTimer(resizeDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	rows, cols, _ := s.Size()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-sigs:
+			debounce.Reset(resizeDebounce)
+		case <-poll:
+			debounce.Reset(0)
+		case <-debounce.C:
+			newRows, newCols, err := s.Size()
+			if err != nil || (newRows == rows && newCols == cols) {
+				continue
+			}
+			rows, cols = newRows, newCols
+			select {
+			case s.resized <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !unix && !windows
+
+package client
+
+import "os"
+
+// Signals that restore a raw terminal before they are handled. A caught
+// signal can't be raised again portably, so none are intercepted and the
+// caller's handlers or the runtime's default run as they would without raw
+// mode.
+var restoreSignals []os.Signal
+
+// Subscribe to terminal resize signals, returns false when the platform has
+// none and the size should be polled instead
+func notifyResize(sigs chan<- os.Signal) bool {
+	return false
+}
+
+// Nothing is intercepted, so there is never a signal to raise again
+func reraise(sig os.Signal) {}
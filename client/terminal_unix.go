@@ -0,0 +1,24 @@
+//go:build unix
+
+package client
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Signals that restore a raw terminal before they are handled
+var restoreSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT}
+
+// Subscribe to terminal resize signals, returns false when the platform has
+// none and the size should be polled instead
+func notifyResize(sigs chan<- os.Signal) bool {
+	signal.Notify(sigs, syscall.SIGWINCH)
+	return true
+}
+
+// Send the signal to the process again now it is no longer being caught
+func reraise(sig os.Signal) {
+	syscall.Kill(os.Getpid(), sig.(syscall.Signal))
+}
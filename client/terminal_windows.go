@@ -0,0 +1,20 @@
+//go:build windows
+
+package client
+
+import "os"
+
+// Signals that restore a raw terminal before they are handled. A raw console
+// reads Ctrl-C as input rather than raising it, and a caught signal can't be
+// raised again, so none are intercepted and the caller's handlers or the
+// runtime's default run as they would without raw mode.
+var restoreSignals []os.Signal
+
+// Subscribe to terminal resize signals, returns false when the platform has
+// none and the size should be polled instead
+func notifyResize(sigs chan<- os.Signal) bool {
+	return false
+}
+
+// Nothing is intercepted, so there is never a signal to raise again
+func reraise(sig os.Signal) {}
@@ -0,0 +1,386 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/gorilla/websocket"
+	"github.com/srepio/sdk/types"
+)
+
+type GetTerminalsRequest struct {
+	ID string `json:"id" param:"id"`
+}
+
+func (r GetTerminalsRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.ID, validation.Required, validation.Match(regexp.MustCompile(uuidRegex))),
+	)
+}
+
+type GetTerminalsResponse struct {
+	Terminals []*types.Terminal `json:"terminals"`
+}
+
+// List the terminals running in a play
+func (c *Client) GetTerminals(ctx context.Context, req *GetTerminalsRequest) (*GetTerminalsResponse, error) {
+	hreq, err := c.buildRequest(http.MethodGet, fmt.Sprintf("/plays/%s/terminals", req.ID), req, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return do[GetTerminalsResponse](ctx, c.hc, hreq)
+}
+
+type CloseTerminalRequest struct {
+	ID       string `json:"id" param:"id"`
+	Terminal string `json:"terminal" param:"terminal"`
+}
+
+func (r CloseTerminalRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.ID, validation.Required, validation.Match(regexp.MustCompile(uuidRegex))),
+		validation.Field(&r.Terminal, validation.Required, validation.Match(regexp.MustCompile(terminalRegex))),
+	)
+}
+
+type CloseTerminalResponse struct{}
+
+// End a terminal in a play, sessions attached to it end with CloseServer
+func (c *Client) CloseTerminal(ctx context.Context, req *CloseTerminalRequest) (*CloseTerminalResponse, error) {
+	hreq, err := c.buildRequest(http.MethodDelete, fmt.Sprintf("/plays/%s/terminals/%s", req.ID, req.Terminal), req, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return do[CloseTerminalResponse](ctx, c.hc, hreq)
+}
+
+type OpenTerminalRequest struct {
+	// Defaults to types.DefaultTerminal
+	Terminal string
+	// The initial size of the terminal
+	Rows uint16
+	Cols uint16
+}
+
+func (r OpenTerminalRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Terminal, validation.Match(regexp.MustCompile(terminalRegex))),
+	)
+}
+
+// Carries several terminals of a play over one socket. Each terminal is
+// driven by its own ShellSession, closing one detaches it from the socket
+// while the terminal keeps running in the play. Output for a slow session
+// holds up the others.
+type TerminalMux struct {
+	sock   Socket
+	base64 bool
+	// The terminal the socket was opened for, its events aren't tagged
+	primary string
+
+	mu    *sync.Mutex
+	terms map[string]*muxSocket
+	play  *types.SocketEvent
+
+	done chan struct{}
+	once *sync.Once
+	err  error
+}
+
+// Open a socket that terminals of the play can be multiplexed over. The
+// socket is opened for req.Terminal, but like any other terminal it has to
+// be opened with Open to be used. Rows and Cols are ignored.
+func (c *Client) OpenTerminalMux(ctx context.Context, req *GetShellRequest) (*TerminalMux, error) {
+	sock, base64, err := c.dialShell(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	m := NewTerminalMux(sock, base64, req.Terminal)
+	go func() {
+		select {
+		case <-ctx.Done():
+			m.Close()
+		case <-m.done:
+		}
+	}()
+	return m, nil
+}
+
+// Multiplex terminals over an already connected socket that was opened for
+// the primary terminal, useful for custom transports and tests
+func NewTerminalMux(sock Socket, base64 bool, primary string) *TerminalMux {
+	if primary == "" {
+		primary = types.DefaultTerminal
+	}
+	m := &TerminalMux{
+		sock:    sock,
+		base64:  base64,
+		primary: primary,
+		mu:      &sync.Mutex{},
+		terms:   map[string]*muxSocket{},
+		done:    make(chan struct{}),
+		once:    &sync.Once{},
+	}
+	go m.readLoop()
+	return m
+}
+
+// Attach to a terminal, creating it in the play when it doesn't exist. The
+// session follows opts.Size when set, RawMode and Reconnect are ignored.
+func (m *TerminalMux) Open(ctx context.Context, req *OpenTerminalRequest, opts *ShellOptions) (*ShellSession, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &ShellOptions{}
+	}
+	id := req.Terminal
+	if id == "" {
+		id = types.DefaultTerminal
+	}
+
+	ms := &muxSocket{
+		m:      m,
+		id:     id,
+		in:     make(chan *types.SocketEvent, sessionOutputBuffer),
+		closed: make(chan struct{}),
+		once:   &sync.Once{},
+	}
+	m.mu.Lock()
+	select {
+	case <-m.done:
+		m.mu.Unlock()
+		return nil, ErrShellClosed
+	default:
+	}
+	if _, ok := m.terms[id]; ok {
+		m.mu.Unlock()
+		return nil, ErrTerminalOpen
+	}
+	m.terms[id] = ms
+	// Sessions opened after connecting still learn which play they are on
+	if m.play != nil {
+		ms.in <- m.play
+	}
+	m.mu.Unlock()
+
+	ev, err := types.EncodeEvent(&types.TerminalOpenPayload{Rows: req.Rows, Cols: req.Cols})
+	if err == nil {
+		err = ms.Write(ev)
+	}
+	if err != nil {
+		m.forget(ms)
+		return nil, err
+	}
+
+	s := newShellSession(ms, opts)
+	s.base64 = m.base64
+	m.mu.Lock()
+	ms.session = s
+	m.mu.Unlock()
+	s.start(ctx)
+	s.Resize(req.Rows, req.Cols)
+	if opts.Size != nil {
+		go s.follow(opts.Size)
+	}
+	return s, nil
+}
+
+// End every session and close the socket, the terminals keep running
+func (m *TerminalMux) Close() error {
+	m.mu.Lock()
+	sessions := []*ShellSession{}
+	for _, ms := range m.terms {
+		if ms.session != nil {
+			sessions = append(sessions, ms.session)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, s := range sessions {
+		s.Close()
+	}
+	m.fail(ErrShellClosed)
+	return nil
+}
+
+// Closed once the socket has closed
+func (m *TerminalMux) Done() <-chan struct{} {
+	return m.done
+}
+
+// Why the socket closed, nil while it is open
+func (m *TerminalMux) Err() error {
+	select {
+	case <-m.done:
+		return m.err
+	default:
+		return nil
+	}
+}
+
+func (m *TerminalMux) fail(err error) {
+	m.once.Do(func() {
+		m.err = err
+		close(m.done)
+		m.sock.Close()
+	})
+}
+
+func (m *TerminalMux) forget(ms *muxSocket) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.terms[ms.id] == ms {
+		delete(m.terms, ms.id)
+	}
+}
+
+// How a terminal is named on the socket
+func (m *TerminalMux) tag(id string) string {
+	if id == m.primary {
+		return ""
+	}
+	return id
+}
+
+func (m *TerminalMux) readLoop() {
+	for {
+		ev, err := m.sock.Read()
+		if err != nil {
+			m.fail(err)
+			return
+		}
+		if ev.Terminal != "" {
+			m.deliver(ev.Terminal, ev)
+			continue
+		}
+
+		switch ev.Type {
+		case types.Ping:
+			// Pings from the server are for the socket rather than a terminal
+			if p, err := types.DecodeAs[types.PingPayload](ev); err == nil {
+				if pong, err := types.EncodeEvent(&types.PongPayload{Data: p.Data}); err == nil {
+					m.sock.Write(pong)
+				}
+			}
+		case types.ActivePlay, types.PlayFinished:
+			m.broadcast(ev)
+		default:
+			m.deliver(m.primary, ev)
+		}
+	}
+}
+
+func (m *TerminalMux) broadcast(ev *types.SocketEvent) {
+	m.mu.Lock()
+	if ev.Type == types.ActivePlay {
+		m.play = ev
+	}
+	terms := make([]*muxSocket, 0, len(m.terms))
+	for _, ms := range m.terms {
+		terms = append(terms, ms)
+	}
+	m.mu.Unlock()
+
+	for _, ms := range terms {
+		ms.deliver(ev)
+	}
+}
+
+// Pass an event to the terminal's session, events for terminals without one
+// are dropped
+func (m *TerminalMux) deliver(id string, ev *types.SocketEvent) {
+	m.mu.Lock()
+	ms, ok := m.terms[id]
+	m.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	if ev.Type == types.TerminalClose {
+		reason := "terminal closed"
+		if p, err := types.DecodeAs[types.TerminalClosePayload](ev); err == nil && p.Reason != "" {
+			reason = p.Reason
+		}
+		ms.end(reason)
+		return
+	}
+	ms.deliver(ev)
+}
+
+// The view of the socket a terminal's session has, events it writes are
+// tagged with the terminal
+type muxSocket struct {
+	m       *TerminalMux
+	id      string
+	session *ShellSession
+
+	in     chan *types.SocketEvent
+	closed chan struct{}
+	once   *sync.Once
+	// Set when the server ended the terminal
+	reason string
+}
+
+func (s *muxSocket) deliver(ev *types.SocketEvent) {
+	select {
+	case s.in <- ev:
+	case <-s.closed:
+	}
+}
+
+func (s *muxSocket) Read() (*types.SocketEvent, error) {
+	select {
+	case ev := <-s.in:
+		return ev, nil
+	case <-s.closed:
+		if s.reason != "" {
+			return nil, &websocket.CloseError{Code: websocket.CloseNormalClosure, Text: s.reason}
+		}
+		return nil, ErrShellClosed
+	case <-s.m.done:
+		return nil, s.m.err
+	}
+}
+
+func (s *muxSocket) Write(msg *types.SocketEvent) error {
+	select {
+	case <-s.closed:
+		return ErrShellClosed
+	case <-s.m.done:
+		return s.m.err
+	default:
+	}
+
+	tagged := *msg
+	tagged.Terminal = s.m.tag(s.id)
+	return s.m.sock.Write(&tagged)
+}
+
+// Detach from the terminal, it keeps running in the play
+func (s *muxSocket) Close() error {
+	s.once.Do(func() {
+		if ev, err := types.EncodeEvent(&types.TerminalDetachPayload{}); err == nil {
+			s.Write(ev)
+		}
+		s.m.forget(s)
+		close(s.closed)
+	})
+	return nil
+}
+
+// The server ended the terminal
+func (s *muxSocket) end(reason string) {
+	s.once.Do(func() {
+		s.reason = reason
+		s.m.forget(s)
+		close(s.closed)
+	})
+}
@@ -0,0 +1,263 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/srepio/sdk/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Fail if the session receives any output for a moment
+func expectSilence(t *testing.T, s *ShellSession) {
+	t.Helper()
+	select {
+	case data := <-s.Output():
+		t.Fatalf("unexpected output %q", data)
+	case <-time.After(time.Millisecond * 100):
+	}
+}
+
+func waitDone(t *testing.T, s *ShellSession) {
+	t.Helper()
+	select {
+	case <-s.Done():
+	case <-time.After(time.Second * 5):
+		t.Fatal("session did not finish")
+	}
+}
+
+func TestNamedTerminals(t *testing.T) {
+	tc := prepareShell(t, nil)
+	ctx := context.Background()
+
+	main, err := tc.client.OpenShell(ctx, &GetShellRequest{ID: tc.playID, Rows: 24, Cols: 80}, nil)
+	require.Nil(t, err)
+	defer main.Close()
+	logs, err := tc.client.OpenShell(ctx, &GetShellRequest{ID: tc.playID, Rows: 30, Cols: 100, Terminal: "logs"}, nil)
+	require.Nil(t, err)
+	defer logs.Close()
+
+	assert.Eventually(t, func() bool {
+		rows, cols := tc.server.TerminalSize(tc.playID, "logs")
+		return rows == 30 && cols == 100
+	}, time.Second, time.Millisecond*10)
+
+	_, err = logs.Write([]byte("tail -f app.log\n"))
+	require.Nil(t, err)
+	readUntil(t, logs, "tail -f app.log\n")
+	expectSilence(t, main)
+
+	resp, err := tc.client.GetTerminals(ctx, &GetTerminalsRequest{ID: tc.playID})
+	require.Nil(t, err)
+	require.Len(t, resp.Terminals, 2)
+	ids := []string{resp.Terminals[0].ID, resp.Terminals[1].ID}
+	assert.ElementsMatch(t, []string{types.DefaultTerminal, "logs"}, ids)
+
+	// Closing a terminal ends the shells attached to it and nothing else
+	_, err = tc.client.CloseTerminal(ctx, &CloseTerminalRequest{ID: tc.playID, Terminal: "logs"})
+	require.Nil(t, err)
+	waitDone(t, logs)
+	assert.Equal(t, CloseServer, logs.Reason())
+
+	_, err = main.Write([]byte("ls\n"))
+	require.Nil(t, err)
+	readUntil(t, main, "ls\n")
+
+	_, err = tc.client.CloseTerminal(ctx, &CloseTerminalRequest{ID: tc.playID, Terminal: "logs"})
+	assert.NotNil(t, err)
+}
+
+func TestTerminalMux(t *testing.T) {
+	tc := prepareShell(t, nil)
+	ctx := context.Background()
+
+	mux, err := tc.client.OpenTerminalMux(ctx, &GetShellRequest{ID: tc.playID})
+	require.Nil(t, err)
+	defer mux.Close()
+
+	main, err := mux.Open(ctx, &OpenTerminalRequest{Rows: 24, Cols: 80}, nil)
+	require.Nil(t, err)
+	logs, err := mux.Open(ctx, &OpenTerminalRequest{Terminal: "logs", Rows: 30, Cols: 100}, nil)
+	require.Nil(t, err)
+	_, err = mux.Open(ctx, &OpenTerminalRequest{Terminal: "logs"}, nil)
+	assert.ErrorIs(t, err, ErrTerminalOpen)
+
+	assert.Eventually(t, func() bool {
+		rows, cols := tc.server.TerminalSize(tc.playID, "logs")
+		return rows == 30 && cols == 100
+	}, time.Second, time.Millisecond*10)
+	assert.Eventually(t, func() bool {
+		return main.Play() != nil && logs.Play() != nil
+	}, time.Second, time.Millisecond*10)
+
+	_, err = logs.Write([]byte("journalctl -f\n"))
+	require.Nil(t, err)
+	readUntil(t, logs, "journalctl -f\n")
+	_, err = main.Write([]byte("ls\n"))
+	require.Nil(t, err)
+	readUntil(t, main, "ls\n")
+	expectSilence(t, logs)
+
+	attached := func(n int) func() bool {
+		return func() bool {
+			resp, err := tc.client.GetTerminals(ctx, &GetTerminalsRequest{ID: tc.playID})
+			require.Nil(t, err)
+			for _, term := range resp.Terminals {
+				if term.ID == "logs" {
+					return term.Attached == n
+				}
+			}
+			return false
+		}
+	}
+
+	// Closing a session detaches it and leaves the terminal running
+	require.Nil(t, logs.Close())
+	assert.Eventually(t, attached(0), time.Second, time.Millisecond*10)
+
+	// Closing the terminal the socket was opened for keeps the socket open
+	// for the others
+	logs, err = mux.Open(ctx, &OpenTerminalRequest{Terminal: "logs"}, nil)
+	require.Nil(t, err)
+	assert.Eventually(t, attached(1), time.Second, time.Millisecond*10)
+	_, err = tc.client.CloseTerminal(ctx, &CloseTerminalRequest{ID: tc.playID, Terminal: types.DefaultTerminal})
+	require.Nil(t, err)
+	waitDone(t, main)
+	assert.Equal(t, CloseServer, main.Reason())
+	_, err = logs.Write([]byte("still here\n"))
+	require.Nil(t, err)
+	readUntil(t, logs, "still here\n")
+
+	_, err = tc.client.CheckPlay(ctx, &CheckPlayRequest{ID: tc.playID})
+	require.Nil(t, err)
+	waitDone(t, logs)
+	assert.Equal(t, ClosePlayFinished, logs.Reason())
+	select {
+	case <-mux.Done():
+	case <-time.After(time.Second * 5):
+		t.Fatal("mux did not close")
+	}
+}
+
+func TestGetShellRequestTerminalValidation(t *testing.T) {
+	type testCase struct {
+		request GetShellRequest
+		passes  bool
+	}
+
+	cases := []testCase{
+		{
+			request: GetShellRequest{
+				ID: uuid.NewString(),
+			},
+			passes: true,
+		},
+		{
+			request: GetShellRequest{
+				ID:       uuid.NewString(),
+				Terminal: "logs-1",
+			},
+			passes: true,
+		},
+		{
+			request: GetShellRequest{
+				ID:       uuid.NewString(),
+				Terminal: "../logs",
+			},
+			passes: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("get_shell_terminal_validation_%s_%t", c.request.Terminal, c.passes), func(t *testing.T) {
+			err := c.request.Validate()
+			if c.passes {
+				assert.Nil(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestCloseTerminalRequestValidation(t *testing.T) {
+	type testCase struct {
+		request CloseTerminalRequest
+		passes  bool
+	}
+
+	cases := []testCase{
+		{
+			request: CloseTerminalRequest{
+				ID:       uuid.NewString(),
+				Terminal: "logs",
+			},
+			passes: true,
+		},
+		{
+			request: CloseTerminalRequest{
+				ID: uuid.NewString(),
+			},
+			passes: false,
+		},
+		{
+			request: CloseTerminalRequest{
+				Terminal: "logs",
+			},
+			passes: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("close_terminal_validation_%s_%t", c.request.Terminal, c.passes), func(t *testing.T) {
+			err := c.request.Validate()
+			if c.passes {
+				assert.Nil(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestOpenTerminalRequestValidation(t *testing.T) {
+	type testCase struct {
+		request OpenTerminalRequest
+		passes  bool
+	}
+
+	cases := []testCase{
+		{
+			request: OpenTerminalRequest{},
+			passes:  true,
+		},
+		{
+			request: OpenTerminalRequest{
+				Terminal: "logs",
+			},
+			passes: true,
+		},
+		{
+			request: OpenTerminalRequest{
+				Terminal: "my logs",
+			},
+			passes: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("open_terminal_validation_%s_%t", c.request.Terminal, c.passes), func(t *testing.T) {
+			err := c.request.Validate()
+			if c.passes {
+				assert.Nil(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
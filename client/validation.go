@@ -2,4 +2,6 @@ package client
 
 var (
 	uuidRegex string = "[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}"
+	// Terminal IDs are used in URLs so are kept simple
+	terminalRegex string = "^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$"
 )
@@ -0,0 +1,312 @@
+package clientmock
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/srepio/sdk/client"
+)
+
+// A configurable client.API implementation for unit tests. Each method can
+// be stubbed with its Func field, otherwise canned responses queued with
+// Return are used. Every call is recorded regardless. It must be created
+// with New, the zero value isn't usable.
+type Client struct {
+	*recorder
+
+	StartPlayFunc       func(ctx context.Context, req *client.StartPlayRequest) (*client.StartPlayResponse, error)
+	CheckPlayFunc       func(ctx context.Context, req *client.CheckPlayRequest) (*client.CheckPlayResponse, error)
+	CancelPlayFunc      func(ctx context.Context, req *client.CancelPlayRequest) (*client.CancelPlayResponse, error)
+	GetPlaysFunc        func(ctx context.Context, req *client.GetPlaysRequest) (*client.GetPlaysResponse, error)
+	GetActivePlayFunc   func(ctx context.Context, req *client.GetActivePlayRequest) (*client.GetActivePlayResponse, error)
+	GetPlayFunc         func(ctx context.Context, req *client.GetPlayRequest) (*client.GetPlayResponse, error)
+	ExecFunc            func(ctx context.Context, req *client.ExecRequest) (*client.ExecResponse, error)
+	CreateUserFunc      func(ctx context.Context, req *client.CreateUserRequest) (*client.CreateUserResponse, error)
+	LoginFunc           func(ctx context.Context, req *client.LoginRequest) (*client.LoginResponse, error)
+	VerifyMFAFunc       func(ctx context.Context, req *client.VerifyMFARequest) (*client.LoginResponse, error)
+	MeFunc              func(ctx context.Context, req *client.MeRequest) (*client.MeResponse, error)
+	GetApiTokensFunc    func(ctx context.Context, req *client.GetApiTokensRequest) (*client.GetApiTokensResponse, error)
+	CreateApiTokenFunc  func(ctx context.Context, req *client.CreateApiTokenRequest) (*client.CreateApiTokenResponse, error)
+	DeleteApiTokenFunc  func(ctx context.Context, req *client.DeleteApiTokenRequest) (*client.DeleteApiTokenResponse, error)
+	ConfirmPasswordFunc func(ctx context.Context, req *client.ConfirmPasswordRequest) (*client.ConfirmPasswordResponse, error)
+	UpdatePasswordFunc  func(ctx context.Context, req *client.UpdatePasswordRequest) (*client.UpdatePasswordResponse, error)
+	ConfigureMFAFunc    func(ctx context.Context, req *client.ConfigureMFARequest) (*client.ConfigureMFAResponse, error)
+	RemoveMFAFunc       func(ctx context.Context, req *client.RemoveMFARequest) (*client.RemoveMFAResponse, error)
+	LogoutFunc          func(ctx context.Context, req *client.LogoutRequest) (*client.LogoutResponse, error)
+	DeleteAccountFunc   func(ctx context.Context, req *client.DeleteAccountRequest) (*client.DeleteAccountResponse, error)
+	GetScenariosFunc    func(ctx context.Context, req *client.GetScenariosRequest) (*client.GetScenariosResponse, error)
+	FindScenarioFunc    func(ctx context.Context, req *client.FindScenarioRequest) (*client.FindScenarioResponse, error)
+
+	GetShellFunc         func(ctx context.Context, req *client.GetShellRequest, stdin io.Reader, stdout io.Writer, opts *client.ShellOptions) error
+	GetTerminalShellFunc func(ctx context.Context, req *client.GetShellRequest, stdin *os.File, stdout *os.File) error
+	OpenShellFunc        func(ctx context.Context, req *client.GetShellRequest, opts *client.ShellOptions) (*client.ShellSession, error)
+
+	UploadFileFunc   func(ctx context.Context, req *client.UploadFileRequest) (*client.UploadFileResponse, error)
+	DownloadFileFunc func(ctx context.Context, req *client.DownloadFileRequest, w io.Writer) (*client.DownloadFileResponse, error)
+	UploadTarFunc    func(ctx context.Context, req *client.UploadTarRequest) (*client.UploadTarResponse, error)
+	DownloadTarFunc  func(ctx context.Context, req *client.DownloadTarRequest, w io.Writer) (*client.DownloadTarResponse, error)
+	UploadDirFunc    func(ctx context.Context, req *client.UploadDirRequest) (*client.UploadTarResponse, error)
+	DownloadDirFunc  func(ctx context.Context, req *client.DownloadDirRequest) (*client.DownloadTarResponse, error)
+
+	ForwardFunc func(ctx context.Context, req *client.ForwardRequest) (*client.Forwarder, error)
+
+	GetTerminalsFunc    func(ctx context.Context, req *client.GetTerminalsRequest) (*client.GetTerminalsResponse, error)
+	CloseTerminalFunc   func(ctx context.Context, req *client.CloseTerminalRequest) (*client.CloseTerminalResponse, error)
+	OpenTerminalMuxFunc func(ctx context.Context, req *client.GetShellRequest) (*client.TerminalMux, error)
+
+	GetSpectatorsFunc   func(ctx context.Context, req *client.GetSpectatorsRequest) (*client.GetSpectatorsResponse, error)
+	AddSpectatorFunc    func(ctx context.Context, req *client.AddSpectatorRequest) (*client.AddSpectatorResponse, error)
+	RemoveSpectatorFunc func(ctx context.Context, req *client.RemoveSpectatorRequest) (*client.RemoveSpectatorResponse, error)
+	WatchShellFunc      func(ctx context.Context, req *client.WatchShellRequest, opts *client.ShellOptions) (*client.ShellSession, error)
+}
+
+var _ client.API = (*Client)(nil)
+
+func New() *Client {
+	return &Client{
+		recorder: newRecorder(),
+	}
+}
+
+func (m *Client) StartPlay(ctx context.Context, req *client.StartPlayRequest) (*client.StartPlayResponse, error) {
+	return handle(m, "StartPlay", ctx, req, m.StartPlayFunc)
+}
+
+func (m *Client) CheckPlay(ctx context.Context, req *client.CheckPlayRequest) (*client.CheckPlayResponse, error) {
+	return handle(m, "CheckPlay", ctx, req, m.CheckPlayFunc)
+}
+
+func (m *Client) CancelPlay(ctx context.Context, req *client.CancelPlayRequest) (*client.CancelPlayResponse, error) {
+	return handle(m, "CancelPlay", ctx, req, m.CancelPlayFunc)
+}
+
+func (m *Client) GetPlays(ctx context.Context, req *client.GetPlaysRequest) (*client.GetPlaysResponse, error) {
+	return handle(m, "GetPlays", ctx, req, m.GetPlaysFunc)
+}
+
+func (m *Client) GetActivePlay(ctx context.Context, req *client.GetActivePlayRequest) (*client.GetActivePlayResponse, error) {
+	return handle(m, "GetActivePlay", ctx, req, m.GetActivePlayFunc)
+}
+
+func (m *Client) GetPlay(ctx context.Context, req *client.GetPlayRequest) (*client.GetPlayResponse, error) {
+	return handle(m, "GetPlay", ctx, req, m.GetPlayFunc)
+}
+
+func (m *Client) Exec(ctx context.Context, req *client.ExecRequest) (*client.ExecResponse, error) {
+	return handle(m, "Exec", ctx, req, m.ExecFunc)
+}
+
+func (m *Client) CreateUser(ctx context.Context, req *client.CreateUserRequest) (*client.CreateUserResponse, error) {
+	return handle(m, "CreateUser", ctx, req, m.CreateUserFunc)
+}
+
+func (m *Client) Login(ctx context.Context, req *client.LoginRequest) (*client.LoginResponse, error) {
+	return handle(m, "Login", ctx, req, m.LoginFunc)
+}
+
+func (m *Client) VerifyMFA(ctx context.Context, req *client.VerifyMFARequest) (*client.LoginResponse, error) {
+	return handle(m, "VerifyMFA", ctx, req, m.VerifyMFAFunc)
+}
+
+func (m *Client) Me(ctx context.Context, req *client.MeRequest) (*client.MeResponse, error) {
+	return handle(m, "Me", ctx, req, m.MeFunc)
+}
+
+func (m *Client) GetApiTokens(ctx context.Context, req *client.GetApiTokensRequest) (*client.GetApiTokensResponse, error) {
+	return handle(m, "GetApiTokens", ctx, req, m.GetApiTokensFunc)
+}
+
+func (m *Client) CreateApiToken(ctx context.Context, req *client.CreateApiTokenRequest) (*client.CreateApiTokenResponse, error) {
+	return handle(m, "CreateApiToken", ctx, req, m.CreateApiTokenFunc)
+}
+
+func (m *Client) DeleteApiToken(ctx context.Context, req *client.DeleteApiTokenRequest) (*client.DeleteApiTokenResponse, error) {
+	return handle(m, "DeleteApiToken", ctx, req, m.DeleteApiTokenFunc)
+}
+
+func (m *Client) ConfirmPassword(ctx context.Context, req *client.ConfirmPasswordRequest) (*client.ConfirmPasswordResponse, error) {
+	return handle(m, "ConfirmPassword", ctx, req, m.ConfirmPasswordFunc)
+}
+
+func (m *Client) UpdatePassword(ctx context.Context, req *client.UpdatePasswordRequest) (*client.UpdatePasswordResponse, error) {
+	return handle(m, "UpdatePassword", ctx, req, m.UpdatePasswordFunc)
+}
+
+func (m *Client) ConfigureMFA(ctx context.Context, req *client.ConfigureMFARequest) (*client.ConfigureMFAResponse, error) {
+	return handle(m, "ConfigureMFA", ctx, req, m.ConfigureMFAFunc)
+}
+
+func (m *Client) RemoveMFA(ctx context.Context, req *client.RemoveMFARequest) (*client.RemoveMFAResponse, error) {
+	return handle(m, "RemoveMFA", ctx, req, m.RemoveMFAFunc)
+}
+
+func (m *Client) Logout(ctx context.Context, req *client.LogoutRequest) (*client.LogoutResponse, error) {
+	return handle(m, "Logout", ctx, req, m.LogoutFunc)
+}
+
+func (m *Client) DeleteAccount(ctx context.Context, req *client.DeleteAccountRequest) (*client.DeleteAccountResponse, error) {
+	return handle(m, "DeleteAccount", ctx, req, m.DeleteAccountFunc)
+}
+
+func (m *Client) GetScenarios(ctx context.Context, req *client.GetScenariosRequest) (*client.GetScenariosResponse, error) {
+	return handle(m, "GetScenarios", ctx, req, m.GetScenariosFunc)
+}
+
+func (m *Client) FindScenario(ctx context.Context, req *client.FindScenarioRequest) (*client.FindScenarioResponse, error) {
+	return handle(m, "FindScenario", ctx, req, m.FindScenarioFunc)
+}
+
+func (m *Client) GetShell(ctx context.Context, req *client.GetShellRequest, stdin io.Reader, stdout io.Writer, opts *client.ShellOptions) error {
+	m.record("GetShell", req)
+	if m.GetShellFunc != nil {
+		return m.GetShellFunc(ctx, req, stdin, stdout, opts)
+	}
+	return m.err("GetShell")
+}
+
+func (m *Client) GetTerminalShell(ctx context.Context, req *client.GetShellRequest, stdin *os.File, stdout *os.File) error {
+	m.record("GetTerminalShell", req)
+	if m.GetTerminalShellFunc != nil {
+		return m.GetTerminalShellFunc(ctx, req, stdin, stdout)
+	}
+	return m.err("GetTerminalShell")
+}
+
+// Canned sessions can be built around a fake socket with client.NewShellSession
+func (m *Client) OpenShell(ctx context.Context, req *client.GetShellRequest, opts *client.ShellOptions) (*client.ShellSession, error) {
+	m.record("OpenShell", req)
+	if m.OpenShellFunc != nil {
+		return m.OpenShellFunc(ctx, req, opts)
+	}
+
+	resp, ok := m.next("OpenShell")
+	if !ok {
+		return nil, fmt.Errorf("OpenShell: %w", ErrNoResponse)
+	}
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	s, ok := resp.Value.(*client.ShellSession)
+	if !ok || s == nil {
+		return nil, fmt.Errorf("OpenShell: canned response is %T, expected %T", resp.Value, s)
+	}
+	return s, nil
+}
+
+func (m *Client) UploadFile(ctx context.Context, req *client.UploadFileRequest) (*client.UploadFileResponse, error) {
+	return handle(m, "UploadFile", ctx, req, m.UploadFileFunc)
+}
+
+func (m *Client) DownloadFile(ctx context.Context, req *client.DownloadFileRequest, w io.Writer) (*client.DownloadFileResponse, error) {
+	var fn func(context.Context, *client.DownloadFileRequest) (*client.DownloadFileResponse, error)
+	if m.DownloadFileFunc != nil {
+		fn = func(ctx context.Context, req *client.DownloadFileRequest) (*client.DownloadFileResponse, error) {
+			return m.DownloadFileFunc(ctx, req, w)
+		}
+	}
+	return handle(m, "DownloadFile", ctx, req, fn)
+}
+
+func (m *Client) UploadTar(ctx context.Context, req *client.UploadTarRequest) (*client.UploadTarResponse, error) {
+	return handle(m, "UploadTar", ctx, req, m.UploadTarFunc)
+}
+
+func (m *Client) DownloadTar(ctx context.Context, req *client.DownloadTarRequest, w io.Writer) (*client.DownloadTarResponse, error) {
+	var fn func(context.Context, *client.DownloadTarRequest) (*client.DownloadTarResponse, error)
+	if m.DownloadTarFunc != nil {
+		fn = func(ctx context.Context, req *client.DownloadTarRequest) (*client.DownloadTarResponse, error) {
+			return m.DownloadTarFunc(ctx, req, w)
+		}
+	}
+	return handle(m, "DownloadTar", ctx, req, fn)
+}
+
+func (m *Client) UploadDir(ctx context.Context, req *client.UploadDirRequest) (*client.UploadTarResponse, error) {
+	return handle(m, "UploadDir", ctx, req, m.UploadDirFunc)
+}
+
+func (m *Client) DownloadDir(ctx context.Context, req *client.DownloadDirRequest) (*client.DownloadTarResponse, error) {
+	return handle(m, "DownloadDir", ctx, req, m.DownloadDirFunc)
+}
+
+func (m *Client) Forward(ctx context.Context, req *client.ForwardRequest) (*client.Forwarder, error) {
+	m.record("Forward", req)
+	if m.ForwardFunc != nil {
+		return m.ForwardFunc(ctx, req)
+	}
+
+	resp, ok := m.next("Forward")
+	if !ok {
+		return nil, fmt.Errorf("Forward: %w", ErrNoResponse)
+	}
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	f, ok := resp.Value.(*client.Forwarder)
+	if !ok || f == nil {
+		return nil, fmt.Errorf("Forward: canned response is %T, expected %T", resp.Value, f)
+	}
+	return f, nil
+}
+
+func (m *Client) GetTerminals(ctx context.Context, req *client.GetTerminalsRequest) (*client.GetTerminalsResponse, error) {
+	return handle(m, "GetTerminals", ctx, req, m.GetTerminalsFunc)
+}
+
+func (m *Client) CloseTerminal(ctx context.Context, req *client.CloseTerminalRequest) (*client.CloseTerminalResponse, error) {
+	return handle(m, "CloseTerminal", ctx, req, m.CloseTerminalFunc)
+}
+
+func (m *Client) OpenTerminalMux(ctx context.Context, req *client.GetShellRequest) (*client.TerminalMux, error) {
+	m.record("OpenTerminalMux", req)
+	if m.OpenTerminalMuxFunc != nil {
+		return m.OpenTerminalMuxFunc(ctx, req)
+	}
+
+	resp, ok := m.next("OpenTerminalMux")
+	if !ok {
+		return nil, fmt.Errorf("OpenTerminalMux: %w", ErrNoResponse)
+	}
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	mux, ok := resp.Value.(*client.TerminalMux)
+	if !ok || mux == nil {
+		return nil, fmt.Errorf("OpenTerminalMux: canned response is %T, expected %T", resp.Value, mux)
+	}
+	return mux, nil
+}
+
+func (m *Client) GetSpectators(ctx context.Context, req *client.GetSpectatorsRequest) (*client.GetSpectatorsResponse, error) {
+	return handle(m, "GetSpectators", ctx, req, m.GetSpectatorsFunc)
+}
+
+func (m *Client) AddSpectator(ctx context.Context, req *client.AddSpectatorRequest) (*client.AddSpectatorResponse, error) {
+	return handle(m, "AddSpectator", ctx, req, m.AddSpectatorFunc)
+}
+
+func (m *Client) RemoveSpectator(ctx context.Context, req *client.RemoveSpectatorRequest) (*client.RemoveSpectatorResponse, error) {
+	return handle(m, "RemoveSpectator", ctx, req, m.RemoveSpectatorFunc)
+}
+
+func (m *Client) WatchShell(ctx context.Context, req *client.WatchShellRequest, opts *client.ShellOptions) (*client.ShellSession, error) {
+	m.record("WatchShell", req)
+	if m.WatchShellFunc != nil {
+		return m.WatchShellFunc(ctx, req, opts)
+	}
+
+	resp, ok := m.next("WatchShell")
+	if !ok {
+		return nil, fmt.Errorf("WatchShell: %w", ErrNoResponse)
+	}
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	s, ok := resp.Value.(*client.ShellSession)
+	if !ok || s == nil {
+		return nil, fmt.Errorf("WatchShell: canned response is %T, expected %T", resp.Value, s)
+	}
+	return s, nil
+}
@@ -0,0 +1,67 @@
+package clientmock
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/srepio/sdk/client"
+	"github.com/srepio/sdk/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestItReturnsCannedResponsesInOrder(t *testing.T) {
+	m := New()
+	m.Return("CheckPlay", &client.CheckPlayResponse{Passed: false}, nil)
+	m.Return("CheckPlay", &client.CheckPlayResponse{Passed: true}, nil)
+
+	var api client.API = m
+	req := &client.CheckPlayRequest{ID: uuid.NewString()}
+
+	for _, want := range []bool{false, true, true} {
+		resp, err := api.CheckPlay(context.Background(), req)
+		assert.Nil(t, err)
+		assert.Equal(t, want, resp.Passed)
+	}
+
+	calls := m.CallsTo("CheckPlay")
+	assert.Len(t, calls, 3)
+	assert.Equal(t, req, calls[0].Request)
+}
+
+func TestItUsesFuncOverCannedResponses(t *testing.T) {
+	m := New()
+	m.Return("StartPlay", nil, errors.New("canned"))
+	m.StartPlayFunc = func(ctx context.Context, req *client.StartPlayRequest) (*client.StartPlayResponse, error) {
+		return &client.StartPlayResponse{Play: &types.Play{Scenario: req.Scenario}}, nil
+	}
+
+	resp, err := m.StartPlay(context.Background(), &client.StartPlayRequest{Scenario: "bongo"})
+	assert.Nil(t, err)
+	assert.Equal(t, "bongo", resp.Play.Scenario)
+	assert.True(t, m.Called("StartPlay"))
+	assert.False(t, m.Called("CheckPlay"))
+}
+
+func TestItErrorsWithoutAResponse(t *testing.T) {
+	m := New()
+
+	_, err := m.Me(context.Background(), &client.MeRequest{})
+	assert.ErrorIs(t, err, ErrNoResponse)
+
+	m.Return("Me", &client.LoginResponse{}, nil)
+	_, err = m.Me(context.Background(), &client.MeRequest{})
+	assert.Error(t, err)
+
+	err = m.GetShell(context.Background(), &client.GetShellRequest{}, nil, nil, nil)
+	assert.ErrorIs(t, err, ErrNoResponse)
+	err = m.GetTerminalShell(context.Background(), &client.GetShellRequest{}, nil, nil)
+	assert.ErrorIs(t, err, ErrNoResponse)
+
+	m.Return("GetShell", nil, nil)
+	assert.Nil(t, m.GetShell(context.Background(), &client.GetShellRequest{}, nil, nil, nil))
+
+	m.Reset()
+	assert.Empty(t, m.Calls())
+}
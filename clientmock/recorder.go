@@ -0,0 +1,135 @@
+package clientmock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	ErrNoResponse = errors.New("no response configured")
+)
+
+type Call struct {
+	Method  string
+	Request any
+}
+
+type Response struct {
+	Value any
+	Err   error
+}
+
+type recorder struct {
+	mu        *sync.Mutex
+	calls     []Call
+	responses map[string][]Response
+}
+
+func newRecorder() *recorder {
+	return &recorder{
+		mu:        &sync.Mutex{},
+		responses: map[string][]Response{},
+	}
+}
+
+// Queue a canned response for the method. Responses are returned in the order
+// they were queued, the last one is repeated once the queue is exhausted.
+func (r *recorder) Return(method string, value any, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.responses[method] = append(r.responses[method], Response{Value: value, Err: err})
+}
+
+// All calls made to the mock, in order
+func (r *recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]Call, len(r.calls))
+	copy(out, r.calls)
+	return out
+}
+
+// The calls made to a single method, in order
+func (r *recorder) CallsTo(method string) []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := []Call{}
+	for _, call := range r.calls {
+		if call.Method == method {
+			out = append(out, call)
+		}
+	}
+	return out
+}
+
+func (r *recorder) Called(method string) bool {
+	return len(r.CallsTo(method)) > 0
+}
+
+// Clear recorded calls and canned responses
+func (r *recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = nil
+	r.responses = map[string][]Response{}
+}
+
+func (r *recorder) record(method string, req any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, Call{Method: method, Request: req})
+}
+
+func (r *recorder) next(method string) (Response, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	queue := r.responses[method]
+	if len(queue) == 0 {
+		return Response{}, false
+	}
+	if len(queue) > 1 {
+		r.responses[method] = queue[1:]
+	}
+	return queue[0], true
+}
+
+// The canned error for methods that only return an error, queue a nil error
+// with Return for them to succeed
+func (r *recorder) err(method string) error {
+	resp, ok := r.next(method)
+	if !ok {
+		return fmt.Errorf("%s: %w", method, ErrNoResponse)
+	}
+	return resp.Err
+}
+
+func handle[Req any, Resp any](m *Client, method string, ctx context.Context, req *Req, fn func(context.Context, *Req) (*Resp, error)) (*Resp, error) {
+	m.record(method, req)
+	if fn != nil {
+		return fn(ctx, req)
+	}
+
+	resp, ok := m.next(method)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", method, ErrNoResponse)
+	}
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	if resp.Value == nil {
+		return new(Resp), nil
+	}
+	out, ok := resp.Value.(*Resp)
+	if !ok {
+		return nil, fmt.Errorf("%s: canned response is %T, expected %T", method, resp.Value, out)
+	}
+	return out, nil
+}
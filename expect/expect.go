@@ -0,0 +1,256 @@
+// Package expect drives play shells from scripts, waiting for output that
+// matches a pattern before sending more input.
+package expect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/srepio/sdk/client"
+)
+
+var (
+	ErrTimeout = errors.New("timed out waiting for output")
+	ErrClosed  = errors.New("shell closed")
+
+	// A shell prompt at the end of the output
+	DefaultPrompt = regexp.MustCompile(`[$#%>] ?$`)
+)
+
+const (
+	defaultTimeout = time.Second * 10
+	// How much of the transcript is included in an error message
+	transcriptTail = 2048
+)
+
+type Options struct {
+	// How long to wait when Expect is given no timeout, defaults to 10s
+	Timeout time.Duration
+	// What ExpectPrompt waits for, defaults to DefaultPrompt
+	Prompt *regexp.Regexp
+	// Match against output as it was sent, escape sequences are stripped by
+	// default
+	Raw bool
+	// Output is copied here as it arrives
+	Log io.Writer
+	// Passed on by Spawn when opening the shell
+	Shell *client.ShellOptions
+}
+
+// Returned when output never matched, it carries everything the shell
+// printed to make failures in CI easier to follow
+type Error struct {
+	Pattern    string
+	Err        error
+	Transcript string
+}
+
+func (e *Error) Error() string {
+	transcript := e.Transcript
+	if len(transcript) > transcriptTail {
+		transcript = "..." + transcript[len(transcript)-transcriptTail:]
+	}
+	return fmt.Sprintf("expect %q: %s\ntranscript:\n%s", e.Pattern, e.Err, transcript)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// The output that matched a pattern
+type Match struct {
+	// Output since the previous match that came before this one
+	Before string
+	// The matched text followed by each capture group, groups that didn't
+	// take part are empty
+	Groups []string
+	names  []string
+}
+
+func (m *Match) Text() string {
+	return m.Groups[0]
+}
+
+// The text captured by a named group
+func (m *Match) Group(name string) string {
+	for i, n := range m.names {
+		if n == name && n != "" {
+			return m.Groups[i]
+		}
+	}
+	return ""
+}
+
+// Reads the output of a shell so scripts can wait for it. It consumes the
+// session's output, nothing else should read from it.
+type Expecter struct {
+	shell *client.ShellSession
+	opts  *Options
+
+	mu         *sync.Mutex
+	buf        string
+	transcript *strings.Builder
+	strip      *stripper
+	closed     bool
+	update     chan struct{}
+}
+
+// Open a shell and start reading its output
+func Spawn(ctx context.Context, c client.Shell, req *client.GetShellRequest, opts *Options) (*Expecter, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	shell, err := c.OpenShell(ctx, req, opts.Shell)
+	if err != nil {
+		return nil, err
+	}
+	return New(shell, opts), nil
+}
+
+// Start reading the output of an open shell
+func New(shell *client.ShellSession, opts *Options) *Expecter {
+	if opts == nil {
+		opts = &Options{}
+	}
+	o := *opts
+	if o.Timeout <= 0 {
+		o.Timeout = defaultTimeout
+	}
+	if o.Prompt == nil {
+		o.Prompt = DefaultPrompt
+	}
+
+	e := &Expecter{
+		shell:      shell,
+		opts:       &o,
+		mu:         &sync.Mutex{},
+		transcript: &strings.Builder{},
+		strip:      &stripper{},
+		update:     make(chan struct{}, 1),
+	}
+	go e.read()
+	return e
+}
+
+func (e *Expecter) read() {
+	for data := range e.shell.Output() {
+		if e.opts.Log != nil {
+			e.opts.Log.Write(data)
+		}
+
+		text := string(data)
+		if !e.opts.Raw {
+			text = e.strip.strip(data)
+		}
+		e.mu.Lock()
+		e.buf += text
+		e.transcript.WriteString(text)
+		e.mu.Unlock()
+		e.notify()
+	}
+
+	e.mu.Lock()
+	e.closed = true
+	e.mu.Unlock()
+	e.notify()
+}
+
+func (e *Expecter) notify() {
+	select {
+	case e.update <- struct{}{}:
+	default:
+	}
+}
+
+// The session being driven
+func (e *Expecter) Shell() *client.ShellSession {
+	return e.shell
+}
+
+// Type input into the shell
+func (e *Expecter) Send(input string) error {
+	_, err := e.shell.Write([]byte(input))
+	return err
+}
+
+// Type a line into the shell followed by enter
+func (e *Expecter) SendLine(line string) error {
+	return e.Send(line + "\r")
+}
+
+// Wait for output matching the pattern. Output up to the end of the match is
+// consumed, so the next call only sees what came after it. A timeout of zero
+// uses the default.
+func (e *Expecter) Expect(re *regexp.Regexp, timeout time.Duration) (*Match, error) {
+	if timeout <= 0 {
+		timeout = e.opts.Timeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		e.mu.Lock()
+		if loc := re.FindStringSubmatchIndex(e.buf); loc != nil {
+			m := &Match{
+				Before: e.buf[:loc[0]],
+				Groups: make([]string, len(loc)/2),
+				names:  re.SubexpNames(),
+			}
+			for i := range m.Groups {
+				if loc[2*i] >= 0 {
+					m.Groups[i] = e.buf[loc[2*i]:loc[2*i+1]]
+				}
+			}
+			e.buf = e.buf[loc[1]:]
+			e.mu.Unlock()
+			return m, nil
+		}
+		closed := e.closed
+		e.mu.Unlock()
+
+		if closed {
+			err := ErrClosed
+			if serr := e.shell.Err(); serr != nil {
+				err = fmt.Errorf("%w: %w", ErrClosed, serr)
+			}
+			return nil, e.fail(re, err)
+		}
+
+		select {
+		case <-e.update:
+		case <-timer.C:
+			return nil, e.fail(re, fmt.Errorf("%w after %s", ErrTimeout, timeout))
+		}
+	}
+}
+
+// Wait for the shell's prompt
+func (e *Expecter) ExpectPrompt(timeout time.Duration) (*Match, error) {
+	return e.Expect(e.opts.Prompt, timeout)
+}
+
+// Everything the shell has printed so far
+func (e *Expecter) Transcript() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.transcript.String()
+}
+
+// Close the shell
+func (e *Expecter) Close() error {
+	return e.shell.Close()
+}
+
+func (e *Expecter) fail(re *regexp.Regexp, err error) error {
+	return &Error{
+		Pattern:    re.String(),
+		Err:        err,
+		Transcript: e.Transcript(),
+	}
+}
@@ -0,0 +1,170 @@
+package expect
+
+import (
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/srepio/sdk/client"
+	"github.com/srepio/sdk/srepfake"
+	"github.com/srepio/sdk/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const prompt = "\x1b[32mbongo@play\x1b[0m:~$ "
+
+// A shell that answers each line with canned output and a coloured prompt
+type scriptedSocket struct {
+	mu      *sync.Mutex
+	line    []byte
+	replies map[string]string
+	out     chan *types.SocketEvent
+	closed  chan struct{}
+	once    *sync.Once
+}
+
+func newScriptedSocket(replies map[string]string) *scriptedSocket {
+	s := &scriptedSocket{
+		mu:      &sync.Mutex{},
+		replies: replies,
+		out:     make(chan *types.SocketEvent, 16),
+		closed:  make(chan struct{}),
+		once:    &sync.Once{},
+	}
+	s.output(prompt)
+	return s
+}
+
+func (s *scriptedSocket) output(data string) {
+	ev, _ := types.EncodeEvent(&types.OutputPayload{Data: []byte(data)})
+	s.out <- ev
+}
+
+func (s *scriptedSocket) Read() (*types.SocketEvent, error) {
+	select {
+	case ev := <-s.out:
+		return ev, nil
+	case <-s.closed:
+		return nil, io.EOF
+	}
+}
+
+func (s *scriptedSocket) Write(msg *types.SocketEvent) error {
+	in, err := types.DecodeAs[types.InputPayload](msg)
+	if err != nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, b := range in.Data {
+		if b != '\r' {
+			s.line = append(s.line, b)
+			continue
+		}
+		line := string(s.line)
+		s.line = nil
+		s.output(line + "\r\n" + s.replies[line] + prompt)
+	}
+	return nil
+}
+
+func (s *scriptedSocket) Close() error {
+	s.once.Do(func() { close(s.closed) })
+	return nil
+}
+
+func spawn(t *testing.T, replies map[string]string, opts *Options) *Expecter {
+	sock := newScriptedSocket(replies)
+	e := New(client.NewShellSession(context.Background(), sock, nil), opts)
+	t.Cleanup(func() { e.Close() })
+	return e
+}
+
+func TestExpect(t *testing.T) {
+	e := spawn(t, map[string]string{
+		"kubectl get pods": "NAME        STATUS\r\nweb-7d9f   \x1b[31mCrashLoopBackOff\x1b[0m\r\n",
+	}, nil)
+
+	_, err := e.ExpectPrompt(time.Second)
+	require.Nil(t, err)
+
+	require.Nil(t, e.SendLine("kubectl get pods"))
+	m, err := e.Expect(regexp.MustCompile(`(?P<pod>web-\w+)\s+(\w+)`), time.Second)
+	require.Nil(t, err)
+	assert.Equal(t, "web-7d9f", m.Group("pod"))
+	assert.Equal(t, "CrashLoopBackOff", m.Groups[2])
+	assert.Equal(t, "web-7d9f   CrashLoopBackOff", m.Text())
+	assert.Equal(t, "kubectl get pods\r\nNAME        STATUS\r\n", m.Before)
+
+	m, err = e.ExpectPrompt(time.Second)
+	require.Nil(t, err)
+	assert.Equal(t, "\r\nbongo@play:~", m.Before)
+}
+
+func TestExpectTimesOut(t *testing.T) {
+	e := spawn(t, nil, &Options{Raw: true})
+
+	_, err := e.Expect(regexp.MustCompile(`never`), 50*time.Millisecond)
+	assert.ErrorIs(t, err, ErrTimeout)
+
+	var eerr *Error
+	require.True(t, errors.As(err, &eerr))
+	assert.Equal(t, "never", eerr.Pattern)
+	assert.Equal(t, prompt, eerr.Transcript)
+	assert.Contains(t, err.Error(), prompt)
+}
+
+func TestExpectAfterTheShellCloses(t *testing.T) {
+	e := spawn(t, nil, nil)
+	e.Close()
+
+	_, err := e.Expect(regexp.MustCompile(`never`), time.Second)
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestStripSplitSequences(t *testing.T) {
+	s := &stripper{}
+	out := &strings.Builder{}
+	for _, b := range []byte("a\x1b[1;3m\x1b[2\x1b(0\x1b]0;title\x07\x1b]2;x\x1b\\bmc") {
+		out.WriteString(s.strip([]byte{b}))
+	}
+	assert.Equal(t, "abmc", out.String())
+}
+
+func TestSolvingAPlay(t *testing.T) {
+	s := srepfake.New(nil)
+	defer s.Close()
+	_, token := s.NewUser("Bongo", "bongo@srep.io", "hunter2hunter2")
+	c := client.NewClient(&client.ClientOptions{
+		Url:    s.Host(),
+		Scheme: "http",
+		Token:  token,
+	})
+	ctx := context.Background()
+	started, err := c.StartPlay(ctx, &client.StartPlayRequest{Scenario: "mango"})
+	require.Nil(t, err)
+
+	e, err := Spawn(ctx, c, &client.GetShellRequest{ID: started.Play.ID}, nil)
+	require.Nil(t, err)
+	defer e.Close()
+
+	require.Nil(t, e.SendLine("systemctl restart web"))
+	m, err := e.Expect(regexp.MustCompile(`restart (\w+)`), time.Second)
+	require.Nil(t, err)
+	assert.Equal(t, "web", m.Groups[1])
+
+	check, err := c.CheckPlay(ctx, &client.CheckPlayRequest{ID: started.Play.ID})
+	require.Nil(t, err)
+	assert.True(t, check.Passed)
+
+	// The play finishing closes the shell
+	_, err = e.Expect(regexp.MustCompile(`never`), time.Second)
+	assert.ErrorIs(t, err, ErrClosed)
+}
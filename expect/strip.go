@@ -0,0 +1,68 @@
+package expect
+
+import (
+	"strings"
+)
+
+type stripState int
+
+const (
+	text stripState = iota
+	escape
+	charset
+	csi
+	osc
+	oscEscape
+)
+
+// Removes escape sequences from output, sequences split across chunks are
+// carried over to the next one
+type stripper struct {
+	state stripState
+}
+
+func (s *stripper) strip(data []byte) string {
+	out := &strings.Builder{}
+	for _, b := range data {
+		switch s.state {
+		case text:
+			if b == 0x1b {
+				s.state = escape
+				continue
+			}
+			out.WriteByte(b)
+		case escape:
+			switch b {
+			case '[':
+				s.state = csi
+			case ']':
+				s.state = osc
+			case '(', ')', '*', '+':
+				s.state = charset
+			default:
+				s.state = text
+			}
+		case charset:
+			// The byte picking the character set
+			s.state = text
+		case csi:
+			switch {
+			case b == 0x1b:
+				// A new sequence abandons this one
+				s.state = escape
+			case b >= 0x40 && b <= 0x7e:
+				s.state = text
+			}
+		case osc:
+			switch b {
+			case 0x07:
+				s.state = text
+			case 0x1b:
+				s.state = oscEscape
+			}
+		case oscEscape:
+			s.state = text
+		}
+	}
+	return out.String()
+}
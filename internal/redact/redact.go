@@ -0,0 +1,120 @@
+// Package redact removes credentials from wire data and terminal text before
+// it is logged or written to recordings.
+package redact
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Written in place of anything redacted
+const Marker = "[REDACTED]"
+
+var (
+	sensitiveHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+	sensitiveFields  = map[string]bool{
+		"password":                  true,
+		"current_password":          true,
+		"new_password":              true,
+		"new_password_confirmation": true,
+		"token":                     true,
+		"secret":                    true,
+		// The one time code sent to verify MFA
+		"code":           true,
+		"recovery_codes": true,
+		// The otpauth:// URL returned when MFA is set up embeds the TOTP
+		// secret
+		"url": true,
+	}
+
+	// Anything that looks like a credential in terminal text. When a pattern
+	// has a group named secret only that part of the match is masked.
+	Secrets = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)bearer\s+(?P<secret>[A-Za-z0-9._~+/-]{8,}=*)`),
+		regexp.MustCompile(`(?i)(?:password|passwd|secret|token|api[_-]?key)\s*[=:]\s*(?P<secret>[^\s'"]+)`),
+		regexp.MustCompile(`eyJ[A-Za-z0-9_-]{4,}\.[A-Za-z0-9_-]{4,}\.[A-Za-z0-9_-]{4,}`),
+		regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+		regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`),
+	}
+	// Output that asks for something secret, the next line of input should
+	// be masked entirely
+	Prompts = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)(password|passphrase|passcode|token|secret)[^\n:]*:\s*$`),
+	}
+)
+
+// Returns a copy of the header with credentials replaced
+func Header(header http.Header) http.Header {
+	out := header.Clone()
+	if out == nil {
+		return http.Header{}
+	}
+	for _, key := range sensitiveHeaders {
+		if out.Get(key) != "" {
+			out.Set(key, Marker)
+		}
+	}
+	return out
+}
+
+// Replaces sensitive fields such as passwords and tokens in a JSON body,
+// bodies that are not valid JSON are returned as is
+func JSON(body []byte) []byte {
+	var data any
+	if err := json.Unmarshal(body, &data); err != nil {
+		return body
+	}
+	out, err := json.Marshal(value(data))
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+func value(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for key, inner := range val {
+			if sensitiveFields[strings.ToLower(key)] {
+				val[key] = Marker
+				continue
+			}
+			val[key] = value(inner)
+		}
+	case []any:
+		for i, inner := range val {
+			val[i] = value(inner)
+		}
+	}
+	return v
+}
+
+// Replace the secrets in s with Marker
+func Text(s string, secrets []*regexp.Regexp) string {
+	for _, re := range secrets {
+		group := re.SubexpIndex("secret")
+		s = re.ReplaceAllStringFunc(s, func(match string) string {
+			if group < 0 {
+				return Marker
+			}
+			loc := re.FindStringSubmatchIndex(match)
+			if loc == nil || loc[2*group] < 0 {
+				return Marker
+			}
+			return match[:loc[2*group]] + Marker + match[loc[2*group+1]:]
+		})
+	}
+	return s
+}
+
+// Whether the output ends asking for something secret
+func Prompted(output string, prompts []*regexp.Regexp) bool {
+	for _, re := range prompts {
+		if re.MatchString(output) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package redact
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSON(t *testing.T) {
+	out := JSON([]byte(`{"user":{"name":"bongo"},"tokens":[{"token":"abc"}],"password":"hunter2"}`))
+	assert.JSONEq(t, `{"user":{"name":"bongo"},"tokens":[{"token":"[REDACTED]"}],"password":"[REDACTED]"}`, string(out))
+
+	assert.Equal(t, "not json", string(JSON([]byte("not json"))))
+}
+
+func TestHeader(t *testing.T) {
+	header := http.Header{"Authorization": {"Bearer apitoken"}, "Accept": {"application/json"}}
+	out := Header(header)
+	assert.Equal(t, Marker, out.Get("Authorization"))
+	assert.Equal(t, "application/json", out.Get("Accept"))
+	assert.Equal(t, "Bearer apitoken", header.Get("Authorization"))
+}
+
+func TestText(t *testing.T) {
+	assert.Equal(t, "Authorization: Bearer "+Marker, Text("Authorization: Bearer abcdefghijkl", Secrets))
+	assert.Equal(t, "export API_KEY="+Marker, Text("export API_KEY=hunter2", Secrets))
+	assert.Equal(t, "ls -la", Text("ls -la", Secrets))
+}
@@ -0,0 +1,144 @@
+package replay
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/srepio/sdk/asciicast"
+)
+
+// Written before replaying from the start when seeking backwards
+const reset = "\x1bc"
+
+type Options struct {
+	// How much faster than recorded to play, defaults to 1
+	Speed float64
+	// Pauses longer than this are cut down to it, defaults to the
+	// recording's idle time limit
+	IdleTimeLimit time.Duration
+	// When set each chunk of output waits for a value from Step rather than
+	// for its time to come round
+	Step <-chan struct{}
+}
+
+// Plays the output of a recording to a writer. Times are on the player's
+// timeline, after idle time has been compressed but before the speed is
+// applied. A Player is not safe for concurrent use.
+type Player struct {
+	w      io.Writer
+	opts   *Options
+	events []asciicast.Event
+	pos    int
+	at     time.Duration
+}
+
+func NewPlayer(rec *Recording, w io.Writer, opts *Options) *Player {
+	if opts == nil {
+		opts = &Options{}
+	}
+	o := *opts
+	if o.Speed <= 0 {
+		o.Speed = 1
+	}
+	if o.IdleTimeLimit <= 0 {
+		o.IdleTimeLimit = time.Duration(rec.Header.IdleTimeLimit * float64(time.Second))
+	}
+
+	p := &Player{w: w, opts: &o}
+	var prev, cut time.Duration
+	for _, ev := range rec.Events {
+		if ev.Code != asciicast.Output {
+			continue
+		}
+		if gap := ev.Time - prev; o.IdleTimeLimit > 0 && gap > o.IdleTimeLimit {
+			cut += gap - o.IdleTimeLimit
+		}
+		prev = ev.Time
+		ev.Time -= cut
+		p.events = append(p.events, ev)
+	}
+	return p
+}
+
+// How long the recording plays for at normal speed
+func (p *Player) Duration() time.Duration {
+	if len(p.events) == 0 {
+		return 0
+	}
+	return p.events[len(p.events)-1].Time
+}
+
+// How far through the recording the player is
+func (p *Player) Position() time.Duration {
+	return p.at
+}
+
+// Play from the current position to the end of the recording, returns early
+// with the context's error when it is cancelled
+func (p *Player) Play(ctx context.Context) error {
+	start := time.Now()
+	from := p.at
+
+	for p.pos < len(p.events) {
+		ev := p.events[p.pos]
+		if p.opts.Step != nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-p.opts.Step:
+			}
+		} else {
+			wait := time.Duration(float64(ev.Time-from)/p.opts.Speed) - time.Since(start)
+			if wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return ctx.Err()
+				case <-timer.C:
+				}
+			}
+		}
+
+		if err := p.Next(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write the next chunk of output straight away, returns io.EOF at the end of
+// the recording
+func (p *Player) Next() error {
+	if p.pos >= len(p.events) {
+		return io.EOF
+	}
+	ev := p.events[p.pos]
+	if _, err := io.WriteString(p.w, ev.Data); err != nil {
+		return err
+	}
+	p.pos++
+	p.at = ev.Time
+	return nil
+}
+
+// Move to a point in the recording, writing the output before it straight
+// away. Seeking backwards resets the terminal and redraws from the start.
+func (p *Player) Seek(to time.Duration) error {
+	to = max(0, min(to, p.Duration()))
+	if to < p.at {
+		if _, err := io.WriteString(p.w, reset); err != nil {
+			return err
+		}
+		p.pos = 0
+	}
+
+	for p.pos < len(p.events) && p.events[p.pos].Time <= to {
+		if err := p.Next(); err != nil {
+			return err
+		}
+	}
+	p.at = to
+	return nil
+}
@@ -0,0 +1,153 @@
+// Package replay plays recorded play shells back to a terminal, from
+// asciicast files or cassettes recorded by the SDK.
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/srepio/sdk/asciicast"
+	"github.com/srepio/sdk/cassette"
+	"github.com/srepio/sdk/types"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown recording format")
+)
+
+// A recorded session as a timeline, whatever format it was loaded from
+type Recording struct {
+	Header asciicast.Header
+	Events []asciicast.Event
+}
+
+// Read a recording from a file
+func Open(path string) (*Recording, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// Read an asciicast or cassette recording, the format is worked out from the
+// content. Only the first socket of a cassette is loaded, use FromCassette
+// for the others.
+func Load(r io.Reader) (*Recording, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var probe struct {
+		Version      int             `json:"version"`
+		Interactions json.RawMessage `json:"interactions"`
+		Sockets      json.RawMessage `json:"sockets"`
+	}
+	if err := json.NewDecoder(bytes.NewReader(raw)).Decode(&probe); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnknownFormat, err)
+	}
+
+	switch {
+	case probe.Version != 0:
+		return ReadAsciicast(bytes.NewReader(raw))
+	case probe.Interactions != nil || probe.Sockets != nil:
+		c := &cassette.Cassette{}
+		if err := json.Unmarshal(raw, c); err != nil {
+			return nil, err
+		}
+		return FromCassette(c, 0)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// Read an asciicast v2 recording
+func ReadAsciicast(r io.Reader) (*Recording, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<24)
+
+	rec := &Recording{}
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: empty recording", ErrUnknownFormat)
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &rec.Header); err != nil {
+		return nil, fmt.Errorf("header: %w", err)
+	}
+	if rec.Header.Version != asciicast.Version {
+		return nil, fmt.Errorf("%w: asciicast version %d", ErrUnknownFormat, rec.Header.Version)
+	}
+
+	for line := 2; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var ev asciicast.Event
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rec.Events = append(rec.Events, ev)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// Build a recording from one of the sockets in a cassette. Cassettes recorded
+// before frames were timed play back all at once, though they can still be
+// stepped through.
+func FromCassette(c *cassette.Cassette, socket int) (*Recording, error) {
+	if socket < 0 || socket >= len(c.Sockets) {
+		return nil, fmt.Errorf("cassette has no socket %d", socket)
+	}
+
+	rec := &Recording{
+		Header: asciicast.Header{Version: asciicast.Version, Width: 80, Height: 24},
+	}
+	sized := false
+	for i, f := range c.Sockets[socket].Frames {
+		switch f.Event.Type {
+		case types.Input, types.Output, types.Resize:
+		default:
+			continue
+		}
+		p, err := types.DecodeEvent(f.Event)
+		if err != nil {
+			return nil, fmt.Errorf("frame %d: %w", i, err)
+		}
+
+		switch p := p.(type) {
+		case *types.OutputPayload:
+			rec.Events = append(rec.Events, asciicast.Event{Time: f.Time, Code: asciicast.Output, Data: string(p.Data)})
+		case *types.InputPayload:
+			rec.Events = append(rec.Events, asciicast.Event{Time: f.Time, Code: asciicast.Input, Data: string(p.Data)})
+		case *types.ResizePayload:
+			// The first size is the one the terminal started with
+			if !sized {
+				rec.Header.Width, rec.Header.Height = int(p.Cols), int(p.Rows)
+				sized = true
+				continue
+			}
+			rec.Events = append(rec.Events, asciicast.Event{Time: f.Time, Code: asciicast.Resize, Data: fmt.Sprintf("%dx%d", p.Cols, p.Rows)})
+		}
+	}
+	return rec, nil
+}
+
+// How long the recording runs for
+func (r *Recording) Duration() time.Duration {
+	if len(r.Events) == 0 {
+		return 0
+	}
+	return r.Events[len(r.Events)-1].Time
+}
@@ -0,0 +1,146 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srepio/sdk/asciicast"
+	"github.com/srepio/sdk/cassette"
+	"github.com/srepio/sdk/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const cast = `{"version":2,"width":100,"height":30,"idle_time_limit":2}
+[0.1,"o","$ "]
+[0.5,"m","ls\n"]
+[0.6,"o","ls\r\n"]
+[10.6,"o","file\r\n"]
+[10.7,"o","$ "]
+`
+
+func TestLoadAsciicast(t *testing.T) {
+	rec, err := Load(strings.NewReader(cast))
+	require.Nil(t, err)
+	assert.Equal(t, 100, rec.Header.Width)
+	assert.Len(t, rec.Events, 5)
+	assert.Equal(t, asciicast.Event{Time: 500 * time.Millisecond, Code: asciicast.Marker, Data: "ls\n"}, rec.Events[1])
+	assert.Equal(t, 10700*time.Millisecond, rec.Duration())
+
+	_, err = Load(strings.NewReader(`{"bongo":true}`))
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+	_, err = Load(strings.NewReader(`{"version":1}`))
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestLoadCassette(t *testing.T) {
+	event := func(p types.Payload) *types.SocketEvent {
+		ev, err := types.EncodeEvent(p)
+		require.Nil(t, err)
+		return ev
+	}
+	output := event(&types.OutputPayload{Data: []byte("ls\r\n")})
+	output.EncodeBase64()
+
+	raw, err := json.Marshal(&cassette.Cassette{
+		Sockets: []*cassette.Socket{{
+			Frames: []*cassette.Frame{
+				{Direction: cassette.Sent, Event: event(&types.ResizePayload{Rows: 30, Cols: 100})},
+				{Direction: cassette.Received, Event: &types.SocketEvent{Type: types.ActivePlay, Content: "{}"}},
+				{Direction: cassette.Sent, Event: event(&types.InputPayload{Data: []byte("ls\n")}), Time: time.Second},
+				{Direction: cassette.Received, Event: output, Time: 2 * time.Second},
+				{Direction: cassette.Sent, Event: event(&types.ResizePayload{Rows: 40, Cols: 120}), Time: 3 * time.Second},
+			},
+		}},
+	})
+	require.Nil(t, err)
+
+	rec, err := Load(bytes.NewReader(raw))
+	require.Nil(t, err)
+	assert.Equal(t, 100, rec.Header.Width)
+	assert.Equal(t, 30, rec.Header.Height)
+	assert.Equal(t, []asciicast.Event{
+		{Time: time.Second, Code: asciicast.Input, Data: "ls\n"},
+		{Time: 2 * time.Second, Code: asciicast.Output, Data: "ls\r\n"},
+		{Time: 3 * time.Second, Code: asciicast.Resize, Data: "120x40"},
+	}, rec.Events)
+}
+
+func TestPlay(t *testing.T) {
+	rec, err := Load(strings.NewReader(cast))
+	require.Nil(t, err)
+
+	out := &bytes.Buffer{}
+	p := NewPlayer(rec, out, &Options{Speed: 20})
+	// The ten second pause is cut down to the recording's two second limit
+	assert.Equal(t, 2700*time.Millisecond, p.Duration())
+
+	start := time.Now()
+	require.Nil(t, p.Play(context.Background()))
+	assert.GreaterOrEqual(t, time.Since(start), p.Duration()/20)
+	assert.Equal(t, "$ ls\r\nfile\r\n$ ", out.String())
+	assert.Equal(t, p.Duration(), p.Position())
+	assert.Equal(t, io.EOF, p.Next())
+}
+
+func TestPlayIsCancelled(t *testing.T) {
+	rec, err := Load(strings.NewReader(cast))
+	require.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	out := &bytes.Buffer{}
+	p := NewPlayer(rec, out, &Options{IdleTimeLimit: time.Hour})
+	assert.ErrorIs(t, p.Play(ctx), context.DeadlineExceeded)
+	assert.Equal(t, "", out.String())
+}
+
+func TestStep(t *testing.T) {
+	rec, err := Load(strings.NewReader(cast))
+	require.Nil(t, err)
+
+	step := make(chan struct{})
+	out := &bytes.Buffer{}
+	p := NewPlayer(rec, out, &Options{Step: step})
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Play(context.Background())
+	}()
+
+	step <- struct{}{}
+	step <- struct{}{}
+	step <- struct{}{}
+	select {
+	case <-done:
+		t.Fatal("finished before the last step")
+	default:
+	}
+	step <- struct{}{}
+	require.Nil(t, <-done)
+	assert.Equal(t, "$ ls\r\nfile\r\n$ ", out.String())
+}
+
+func TestSeek(t *testing.T) {
+	rec, err := Load(strings.NewReader(cast))
+	require.Nil(t, err)
+
+	out := &bytes.Buffer{}
+	p := NewPlayer(rec, out, nil)
+	require.Nil(t, p.Seek(time.Second))
+	assert.Equal(t, "$ ls\r\n", out.String())
+	assert.Equal(t, time.Second, p.Position())
+
+	out.Reset()
+	require.Nil(t, p.Seek(200*time.Millisecond))
+	assert.Equal(t, reset+"$ ", out.String())
+
+	out.Reset()
+	require.Nil(t, p.Seek(time.Hour))
+	assert.Equal(t, "ls\r\nfile\r\n$ ", out.String())
+	assert.Equal(t, p.Duration(), p.Position())
+}
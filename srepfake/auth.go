@@ -0,0 +1,360 @@
+package srepfake
+
+import (
+	"fmt"
+	"net/http"
+	"net/mail"
+	"slices"
+
+	"github.com/srepio/sdk/types"
+)
+
+type user struct {
+	types.User
+	password  string
+	mfa       *types.MFAData
+	apiTokens []types.ApiToken
+	// Maps api token names to their token value
+	apiTokenValues map[string]string
+}
+
+func (u *user) details() *types.UserDetails {
+	return &types.UserDetails{
+		MFAEnabled: u.mfa != nil,
+	}
+}
+
+// Seed a user and return it along with a session token for them
+func (s *Server) NewUser(name, email, password string) (*types.User, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := s.addUser(name, email, password)
+	return &u.User, s.issueToken(u)
+}
+
+func (s *Server) addUser(name, email, password string) *user {
+	u := &user{
+		User: types.User{
+			ID:    newID(),
+			Name:  name,
+			Email: email,
+		},
+		password:       password,
+		apiTokenValues: map[string]string{},
+	}
+	s.users[u.ID] = u
+	return u
+}
+
+func (s *Server) issueToken(u *user) string {
+	token := randomString(32)
+	s.tokens[token] = u.ID
+	return token
+}
+
+func (s *Server) findUserByEmail(email string) *user {
+	for _, u := range s.users {
+		if u.Email == email {
+			return u
+		}
+	}
+	return nil
+}
+
+func (s *Server) createUser(w http.ResponseWriter, r *http.Request) {
+	req := struct {
+		Name     string `json:"name"`
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}{}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, err := mail.ParseAddress(req.Email); err != nil {
+		writeValidationError(w, "email", "must be a valid email address")
+		return
+	}
+	if len(req.Password) < 10 {
+		writeValidationError(w, "password", "the length must be between 10 and 255")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.findUserByEmail(req.Email) != nil {
+		writeValidationError(w, "email", fmt.Sprintf("user %s already exists", req.Email))
+		return
+	}
+	u := s.addUser(req.Name, req.Email, req.Password)
+
+	writeJSON(w, http.StatusCreated, map[string]any{"user": u.User})
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	req := struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}{}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := s.findUserByEmail(req.Email)
+	if u == nil || u.password != req.Password {
+		writeError(w, http.StatusUnauthorized, "invalid credentials")
+		return
+	}
+
+	if u.mfa != nil {
+		id := newID()
+		s.pending[id] = u.ID
+		writeJSON(w, http.StatusOK, map[string]any{
+			"mfa_required":      true,
+			"authentication_id": id,
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"user":    u.User,
+		"token":   s.issueToken(u),
+		"details": u.details(),
+	})
+}
+
+func (s *Server) verifyMFA(w http.ResponseWriter, r *http.Request) {
+	req := struct {
+		AuthenticationID string `json:"authentication_id"`
+		Code             string `json:"code"`
+	}{}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[s.pending[req.AuthenticationID]]
+	if !ok || u.mfa == nil {
+		writeError(w, http.StatusUnauthorized, "invalid authentication id")
+		return
+	}
+
+	if !validTOTP(u.mfa.Secret, req.Code, s.now()) {
+		i := slices.Index(u.mfa.RecoveryCodes[:], req.Code)
+		if i == -1 || req.Code == "" {
+			writeError(w, http.StatusUnauthorized, "invalid code")
+			return
+		}
+		// Recovery codes can only be used once
+		u.mfa.RecoveryCodes[i] = ""
+	}
+	delete(s.pending, req.AuthenticationID)
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"user":    u.User,
+		"token":   s.issueToken(u),
+		"details": u.details(),
+	})
+}
+
+func (s *Server) me(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"user":    u.User,
+		"details": u.details(),
+	})
+}
+
+func (s *Server) getApiTokens(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tokens": slices.Clone(u.apiTokens),
+	})
+}
+
+func (s *Server) createApiToken(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	req := struct {
+		Name string `json:"name"`
+	}{}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Name == "" {
+		writeValidationError(w, "name", "cannot be blank")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := u.apiTokenValues[req.Name]; ok {
+		writeValidationError(w, "name", fmt.Sprintf("token %s already exists", req.Name))
+		return
+	}
+
+	token := s.issueToken(u)
+	u.apiTokenValues[req.Name] = token
+	u.apiTokens = append(u.apiTokens, types.ApiToken{
+		Name:      req.Name,
+		CreatedAt: s.now().Unix(),
+	})
+
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"name":  req.Name,
+		"token": token,
+	})
+}
+
+func (s *Server) deleteApiToken(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	req := struct {
+		Name string `json:"name"`
+	}{}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := u.apiTokenValues[req.Name]
+	if !ok {
+		writeError(w, http.StatusNotFound, "token not found")
+		return
+	}
+	delete(s.tokens, token)
+	delete(u.apiTokenValues, req.Name)
+	u.apiTokens = slices.DeleteFunc(u.apiTokens, func(t types.ApiToken) bool {
+		return t.Name == req.Name
+	})
+
+	writeJSON(w, http.StatusOK, map[string]any{})
+}
+
+func (s *Server) confirmPassword(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	req := struct {
+		Password string `json:"password"`
+	}{}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"confirmed": u.password == req.Password,
+	})
+}
+
+func (s *Server) updatePassword(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	req := struct {
+		CurrentPassword         string `json:"current_password"`
+		NewPassword             string `json:"new_password"`
+		NewPasswordConfirmation string `json:"new_password_confirmation"`
+	}{}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case u.password != req.CurrentPassword:
+		writeValidationError(w, "current_password", "is incorrect")
+	case len(req.NewPassword) < 10:
+		writeValidationError(w, "new_password", "the length must be between 10 and 255")
+	case req.NewPassword != req.NewPasswordConfirmation:
+		writeValidationError(w, "new_password_confirmation", "does not match")
+	default:
+		u.password = req.NewPassword
+		writeJSON(w, http.StatusOK, map[string]any{"updated": true})
+	}
+}
+
+func (s *Server) configureMFA(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	req := struct {
+		Provider types.MFAType `json:"provider"`
+	}{}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Provider != types.TOTP {
+		writeValidationError(w, "provider", "must be a valid value")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := &types.MFAData{
+		Secret: newTOTPSecret(),
+	}
+	data.URL = fmt.Sprintf("otpauth://totp/srep:%s?secret=%s&issuer=srep", u.Email, data.Secret)
+	for i := range data.RecoveryCodes {
+		data.RecoveryCodes[i] = randomString(5)
+	}
+	u.mfa = data
+
+	writeJSON(w, http.StatusOK, map[string]any{"mfa_data": data})
+}
+
+func (s *Server) removeMFA(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u.mfa = nil
+	writeJSON(w, http.StatusOK, map[string]any{})
+}
+
+func (s *Server) logout(w http.ResponseWriter, r *http.Request, _ *user, token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.tokens, token)
+	writeJSON(w, http.StatusOK, map[string]any{})
+}
+
+func (s *Server) deleteAccount(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	req := struct {
+		Password string `json:"password"`
+	}{}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u.password != req.Password {
+		writeValidationError(w, "password", "is incorrect")
+		return
+	}
+	for token, id := range s.tokens {
+		if id == u.ID {
+			delete(s.tokens, token)
+		}
+	}
+	delete(s.users, u.ID)
+
+	writeJSON(w, http.StatusOK, map[string]any{})
+}
@@ -0,0 +1,79 @@
+package srepfake
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/srepio/sdk/types"
+)
+
+// A command run in a play through the exec endpoint
+type Command struct {
+	Play    types.Play
+	Command string
+	Stdin   []byte
+	// Seconds the client allowed, zero when it didn't set a limit
+	Timeout uint
+}
+
+type Result struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+	TimedOut bool
+}
+
+// Runs the few commands the fake understands: echo, cat, true and false.
+// Anything else is not found.
+func DefaultExec(cmd *Command) *Result {
+	name, args, _ := strings.Cut(strings.TrimSpace(cmd.Command), " ")
+	switch name {
+	case "echo":
+		return &Result{Stdout: []byte(args + "\n")}
+	case "cat":
+		return &Result{Stdout: bytes.Clone(cmd.Stdin)}
+	case "true":
+		return &Result{}
+	case "false":
+		return &Result{ExitCode: 1}
+	}
+	return &Result{
+		Stderr:   []byte(fmt.Sprintf("sh: %s: command not found\n", name)),
+		ExitCode: 127,
+	}
+}
+
+func (s *Server) exec(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	req := struct {
+		Command string `json:"command"`
+		Stdin   []byte `json:"stdin"`
+		Timeout uint   `json:"timeout"`
+	}{}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Command == "" {
+		writeError(w, http.StatusUnprocessableEntity, "command is required")
+		return
+	}
+
+	p, ok := s.runningPlay(w, r, u)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	p.history = append(p.history, req.Command)
+	cmd := &Command{Play: p.Play, Command: req.Command, Stdin: req.Stdin, Timeout: req.Timeout}
+	s.mu.Unlock()
+
+	res := s.opts.Exec(cmd)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"stdout":    res.Stdout,
+		"stderr":    res.Stderr,
+		"exit_code": res.ExitCode,
+		"timed_out": res.TimedOut,
+	})
+}
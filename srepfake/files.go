@@ -0,0 +1,232 @@
+package srepfake
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/srepio/sdk/types"
+)
+
+const checksumHeader = "X-Checksum-Sha256"
+
+// A file written to a play, directories only exist through the files in them
+type file struct {
+	data []byte
+	mode int64
+}
+
+// Get a running play for a request, writing the error response when there
+// isn't one
+func (s *Server) runningPlay(w http.ResponseWriter, r *http.Request, u *user) (*play, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.userPlay(u, r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "play not found")
+		return nil, false
+	}
+	if p.Status != types.PlayRunning {
+		writeError(w, http.StatusConflict, "play is not running")
+		return nil, false
+	}
+	return p, true
+}
+
+// The cleaned path from the query, it must be absolute
+func filePath(w http.ResponseWriter, r *http.Request) (string, bool) {
+	p := r.URL.Query().Get("path")
+	if !strings.HasPrefix(p, "/") {
+		writeError(w, http.StatusUnprocessableEntity, "path must be absolute")
+		return "", false
+	}
+	return path.Clean(p), true
+}
+
+func (s *Server) putFile(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	name, ok := filePath(w, r)
+	if !ok {
+		return
+	}
+	mode := int64(0o644)
+	if raw := r.URL.Query().Get("mode"); raw != "" {
+		m, err := strconv.ParseInt(raw, 8, 32)
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, "invalid mode")
+			return
+		}
+		mode = m
+	}
+	p, ok := s.runningPlay(w, r, u)
+	if !ok {
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.mu.Lock()
+	p.writeFile(name, &file{data: data, mode: mode})
+	s.mu.Unlock()
+
+	sum := sha256.Sum256(data)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"path":   name,
+		"size":   len(data),
+		"sha256": hex.EncodeToString(sum[:]),
+	})
+}
+
+func (s *Server) getFile(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	name, ok := filePath(w, r)
+	if !ok {
+		return
+	}
+	p, ok := s.runningPlay(w, r, u)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	f, ok := p.files[name]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "file not found")
+		return
+	}
+
+	sum := sha256.Sum256(f.data)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(len(f.data)))
+	w.Header().Set(checksumHeader, hex.EncodeToString(sum[:]))
+	w.Header().Set("X-File-Mode", strconv.FormatInt(f.mode, 8))
+	w.WriteHeader(http.StatusOK)
+	w.Write(f.data)
+}
+
+func (s *Server) putArchive(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	dir, ok := filePath(w, r)
+	if !ok {
+		return
+	}
+	p, ok := s.runningPlay(w, r, u)
+	if !ok {
+		return
+	}
+
+	sum := sha256.New()
+	var size byteCount
+	body := io.TeeReader(r.Body, io.MultiWriter(sum, &size))
+	tr := tar.NewReader(body)
+	files := map[string]*file{}
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(hdr.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			writeError(w, http.StatusUnprocessableEntity, "archive entry is outside of the directory")
+			return
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		files[path.Join(dir, name)] = &file{data: data, mode: hdr.Mode & 0o777}
+	}
+	io.Copy(io.Discard, body)
+
+	s.mu.Lock()
+	for name, f := range files {
+		p.writeFile(name, f)
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"path":   dir,
+		"files":  len(files),
+		"size":   int64(size),
+		"sha256": hex.EncodeToString(sum.Sum(nil)),
+	})
+}
+
+func (s *Server) getArchive(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	dir, ok := filePath(w, r)
+	if !ok {
+		return
+	}
+	p, ok := s.runningPlay(w, r, u)
+	if !ok {
+		return
+	}
+
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	s.mu.Lock()
+	names := []string{}
+	files := map[string]*file{}
+	for name, f := range p.files {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+			files[name] = f
+		}
+	}
+	s.mu.Unlock()
+	if len(names) == 0 {
+		writeError(w, http.StatusNotFound, "directory not found")
+		return
+	}
+	slices.Sort(names)
+
+	// The archive is streamed so its checksum is only known at the end
+	w.Header().Set("Trailer", checksumHeader)
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.WriteHeader(http.StatusOK)
+
+	sum := sha256.New()
+	tw := tar.NewWriter(io.MultiWriter(w, sum))
+	for _, name := range names {
+		f := files[name]
+		tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     strings.TrimPrefix(name, prefix),
+			Mode:     f.mode,
+			Size:     int64(len(f.data)),
+		})
+		tw.Write(f.data)
+	}
+	tw.Close()
+	w.Header().Set(checksumHeader, hex.EncodeToString(sum.Sum(nil)))
+}
+
+func (p *play) writeFile(name string, f *file) {
+	if p.files == nil {
+		p.files = map[string]*file{}
+	}
+	p.files[name] = f
+}
+
+type byteCount int64
+
+func (c *byteCount) Write(p []byte) (int, error) {
+	*c += byteCount(len(p))
+	return len(p), nil
+}
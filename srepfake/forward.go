@@ -0,0 +1,152 @@
+package srepfake
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/srepio/sdk/types"
+)
+
+// Connects a forwarded stream to a port on the local machine, as though the
+// play's ports were the host's
+func DefaultDial(_ *types.Play, port uint16) (net.Conn, error) {
+	return net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+}
+
+// A forwarded connection, it ends once both sides have closed
+type forwardStream struct {
+	conn     net.Conn
+	sent     bool
+	received bool
+}
+
+type forwarder struct {
+	c       *conn
+	mu      *sync.Mutex
+	streams map[uint32]*forwardStream
+}
+
+func (s *Server) forward(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	p, sh, ok := s.playSockets(w, r, u, s.userPlay)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	snapshot := p.Play
+	s.mu.Unlock()
+
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := newConn(ws)
+	if !sh.add(c) {
+		c.close(websocket.CloseNormalClosure, "play has finished")
+		return
+	}
+	defer sh.remove(c)
+	defer ws.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go s.watch(p, done)
+
+	f := &forwarder{c: c, mu: &sync.Mutex{}, streams: map[uint32]*forwardStream{}}
+	defer f.closeAll()
+
+	for {
+		ev := &types.SocketEvent{}
+		if err := ws.ReadJSON(ev); err != nil {
+			return
+		}
+		payload, err := types.DecodeEvent(ev)
+		if err != nil {
+			continue
+		}
+
+		switch in := payload.(type) {
+		case *types.ForwardOpenPayload:
+			target, err := s.opts.Dial(&snapshot, in.Port)
+			if err != nil {
+				c.send(&types.ForwardClosePayload{Stream: in.Stream, Error: err.Error()})
+				continue
+			}
+			f.mu.Lock()
+			f.streams[in.Stream] = &forwardStream{conn: target}
+			f.mu.Unlock()
+			go f.pump(in.Stream, target)
+		case *types.ForwardDataPayload:
+			if st := f.stream(in.Stream); st != nil {
+				st.conn.Write(in.Data)
+			}
+		case *types.ForwardClosePayload:
+			f.finish(in.Stream, false, in.Error != "")
+		case *types.PingPayload:
+			c.send(&types.PongPayload{Data: in.Data})
+		}
+	}
+}
+
+// Send what the target writes until it closes its side
+func (f *forwarder) pump(id uint32, target net.Conn) {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := target.Read(buf)
+		if n > 0 {
+			f.c.send(&types.ForwardDataPayload{Stream: id, Data: append([]byte{}, buf[:n]...)})
+		}
+		if err == nil {
+			continue
+		}
+
+		msg := &types.ForwardClosePayload{Stream: id}
+		if !errors.Is(err, io.EOF) {
+			msg.Error = err.Error()
+		}
+		f.c.send(msg)
+		f.finish(id, true, msg.Error != "")
+		return
+	}
+}
+
+func (f *forwarder) stream(id uint32) *forwardStream {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.streams[id]
+}
+
+func (f *forwarder) finish(id uint32, sent, failed bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	st, ok := f.streams[id]
+	if !ok {
+		return
+	}
+	if sent {
+		st.sent = true
+	} else {
+		st.received = true
+		if tcp, ok := st.conn.(*net.TCPConn); ok && !failed {
+			tcp.CloseWrite()
+		}
+	}
+	if failed || (st.sent && st.received) {
+		st.conn.Close()
+		delete(f.streams, id)
+	}
+}
+
+func (f *forwarder) closeAll() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for id, st := range f.streams {
+		st.conn.Close()
+		delete(f.streams, id)
+	}
+}
@@ -0,0 +1,259 @@
+package srepfake
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/srepio/sdk/types"
+)
+
+type play struct {
+	types.Play
+	startedAt time.Time
+	history   []string
+	line      []byte
+	files     map[string]*file
+	// IDs of the users the owner has allowed to watch
+	spectators map[string]struct{}
+}
+
+func (p *play) active() bool {
+	return p.Status == types.PlayPending || p.Status == types.PlayRunning
+}
+
+// Move the play along based on the current time, returns true when the play
+// finished as a result. Expired plays are queued for notifyExpired, as their
+// shells can only be told once the server lock is released
+func (s *Server) refresh(p *play) bool {
+	now := s.now()
+	if p.Status == types.PlayPending && !now.Before(p.startedAt.Add(s.opts.BootTime)) {
+		p.Status = types.PlayRunning
+		p.UpdatedAt = now.Unix()
+	}
+	if p.active() && !now.Before(p.startedAt.Add(s.opts.PlayTTL)) {
+		s.finish(p, types.PlayExpired)
+		s.expired = append(s.expired, p)
+		return true
+	}
+	return false
+}
+
+func (s *Server) refreshAll() {
+	for _, p := range s.plays {
+		s.refresh(p)
+	}
+}
+
+func (s *Server) finish(p *play, status types.PlayStatus) {
+	now := s.now().Unix()
+	p.Status = status
+	p.FinishedAt = &now
+	p.UpdatedAt = now
+}
+
+// Tell any open shells that their play has finished, must not be called
+// while holding the server lock
+func (s *Server) notify(plays []*play) {
+	for _, p := range plays {
+		s.mu.Lock()
+		sh, ok := s.shells[p.ID]
+		delete(s.shells, p.ID)
+		snapshot := p.Play
+		s.mu.Unlock()
+
+		if ok {
+			sh.finish(&snapshot)
+		}
+	}
+}
+
+// Tell the shells of any plays that expired during a refresh, must not be
+// called while holding the server lock
+func (s *Server) notifyExpired() {
+	s.mu.Lock()
+	expired := s.expired
+	s.expired = nil
+	s.mu.Unlock()
+
+	s.notify(expired)
+}
+
+// Get a play owned by the user, refreshing its status
+func (s *Server) userPlay(u *user, id string) (*play, bool) {
+	p, ok := s.plays[id]
+	if !ok || p.UserID != u.ID {
+		return nil, false
+	}
+	s.refresh(p)
+	return p, true
+}
+
+func (s *Server) activePlay(u *user) *play {
+	for _, p := range s.plays {
+		if p.UserID != u.ID {
+			continue
+		}
+		s.refresh(p)
+		if p.active() {
+			return p
+		}
+	}
+	return nil
+}
+
+func (s *Server) userPlays(u *user, scenario string) []types.Play {
+	out := []types.Play{}
+	for _, p := range s.plays {
+		if p.UserID != u.ID || (scenario != "" && p.Scenario != scenario) {
+			continue
+		}
+		s.refresh(p)
+		out = append(out, p.Play)
+	}
+	slices.SortFunc(out, func(a, b types.Play) int {
+		return cmp.Compare(b.CreatedAt, a.CreatedAt)
+	})
+	return out
+}
+
+func (s *Server) startPlay(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	req := struct {
+		Scenario string `json:"scenario"`
+	}{}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.scenario(req.Scenario) == nil {
+		writeError(w, http.StatusNotFound, "scenario not found")
+		return
+	}
+	if s.activePlay(u) != nil {
+		writeError(w, http.StatusConflict, "a play is already active")
+		return
+	}
+
+	now := s.now()
+	p := &play{
+		Play: types.Play{
+			ID:        newID(),
+			UserID:    u.ID,
+			Scenario:  req.Scenario,
+			Status:    types.PlayPending,
+			CreatedAt: now.Unix(),
+			UpdatedAt: now.Unix(),
+		},
+		startedAt: now,
+	}
+	s.plays[p.ID] = p
+	s.refresh(p)
+
+	writeJSON(w, http.StatusCreated, map[string]any{"play": p.Play})
+}
+
+func (s *Server) checkPlay(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	req := struct {
+		ID string `json:"id"`
+	}{}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	p, ok := s.userPlay(u, req.ID)
+	if !ok {
+		s.mu.Unlock()
+		writeError(w, http.StatusNotFound, "play not found")
+		return
+	}
+	if p.Status != types.PlayRunning {
+		s.mu.Unlock()
+		writeError(w, http.StatusConflict, "play is not running")
+		return
+	}
+	snapshot := p.Play
+	s.mu.Unlock()
+
+	passed := s.opts.Check(&snapshot)
+
+	s.mu.Lock()
+	finished := passed && p.Status == types.PlayRunning
+	if finished {
+		s.finish(p, types.PlayCompleted)
+	}
+	s.mu.Unlock()
+
+	if finished {
+		s.notify([]*play{p})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"passed": passed})
+}
+
+func (s *Server) cancelPlay(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	req := struct {
+		ID string `json:"id"`
+	}{}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	p, ok := s.userPlay(u, req.ID)
+	if !ok {
+		s.mu.Unlock()
+		writeError(w, http.StatusNotFound, "play not found")
+		return
+	}
+	if !p.active() {
+		s.mu.Unlock()
+		writeError(w, http.StatusConflict, "play is not active")
+		return
+	}
+	s.finish(p, types.PlayCancelled)
+	s.mu.Unlock()
+
+	s.notify([]*play{p})
+	writeJSON(w, http.StatusOK, map[string]any{})
+}
+
+func (s *Server) getPlays(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{"plays": s.userPlays(u, "")})
+}
+
+func (s *Server) getActivePlay(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.activePlay(u)
+	if p == nil {
+		writeError(w, http.StatusNotFound, "no active play")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"play": p.Play})
+}
+
+func (s *Server) getPlay(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.userPlay(u, r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "play not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"play":    p.Play,
+		"history": slices.Clone(p.history),
+	})
+}
@@ -0,0 +1,83 @@
+package srepfake
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/srepio/sdk/types"
+)
+
+func (s *Server) scenario(name string) *types.Scenario {
+	for _, sc := range s.opts.Scenarios {
+		if sc.Name == name {
+			return &sc
+		}
+	}
+	return nil
+}
+
+func (s *Server) played(u *user, scenario string) *bool {
+	if u == nil {
+		return nil
+	}
+	played := false
+	for _, p := range s.plays {
+		if p.UserID == u.ID && p.Scenario == scenario {
+			played = true
+			break
+		}
+	}
+	return &played
+}
+
+func (s *Server) getScenarios(w http.ResponseWriter, r *http.Request) {
+	u := s.optionalUser(r)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := types.Metadata{}
+	for _, sc := range s.opts.Scenarios {
+		sc.Played = s.played(u, sc.Name)
+		out = append(out, sc)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"scenarios": out})
+}
+
+func (s *Server) findScenario(w http.ResponseWriter, r *http.Request) {
+	u := s.optionalUser(r)
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sc := s.scenario(r.PathValue("name"))
+	if sc == nil {
+		writeError(w, http.StatusNotFound, "scenario not found")
+		return
+	}
+	sc.Played = s.played(u, sc.Name)
+
+	resp := map[string]any{"scenario": sc}
+	if u != nil {
+		resp["history"] = paginate(s.userPlays(u, sc.Name), page, s.opts.PerPage)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func paginate(plays []types.Play, page, perPage int) *types.Paginated[*types.Play] {
+	total := len(plays)
+	pages := (total + perPage - 1) / perPage
+
+	data := []*types.Play{}
+	for i := (page - 1) * perPage; i < total && i < page*perPage; i++ {
+		data = append(data, &plays[i])
+	}
+
+	return types.NewPaginated(total, page, pages, perPage, data)
+}
@@ -0,0 +1,247 @@
+// Package srepfake provides an in-memory fake of the srep API for testing
+// tooling built on the SDK without talking to api.srep.io.
+package srepfake
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/srepio/sdk/types"
+)
+
+var (
+	DefaultScenarios = types.Metadata{
+		{
+			Name:        "mango",
+			Description: "Fix the broken deployment",
+			Difficulty:  "easy",
+			Version:     "1.0.0",
+			Tags:        []string{"kubernetes"},
+		},
+		{
+			Name:        "papaya",
+			Description: "Find out why the disk is full",
+			Difficulty:  "medium",
+			Version:     "1.0.0",
+			Tags:        []string{"linux"},
+		},
+	}
+)
+
+type Options struct {
+	// The scenarios that can be played, defaults to DefaultScenarios
+	Scenarios types.Metadata
+	// How long a play stays PENDING before it is RUNNING
+	BootTime time.Duration
+	// How long a play can run before it is EXPIRED, defaults to an hour
+	PlayTTL time.Duration
+	// Page size for paginated responses, defaults to 10
+	PerPage int
+	// Decides whether a play passes its check, defaults to always passing
+	Check func(play *types.Play) bool
+	// Runs commands sent to the exec endpoint, defaults to DefaultExec
+	Exec func(cmd *Command) *Result
+	// Connects forwarded ports, defaults to DefaultDial
+	Dial func(play *types.Play, port uint16) (net.Conn, error)
+	// How often the shell sends ping events, disabled when zero
+	PingInterval time.Duration
+	// Don't negotiate base64 encoded shell events, like older servers
+	TextOnly bool
+}
+
+type Server struct {
+	*httptest.Server
+
+	opts   *Options
+	mu     *sync.Mutex
+	offset time.Duration
+
+	users   map[string]*user
+	tokens  map[string]string
+	pending map[string]string
+	plays   map[string]*play
+	shells  map[string]*shell
+	// Plays that expired while the lock was held, waiting on notifyExpired
+	expired []*play
+}
+
+// Start a new fake server, it should be closed once finished with
+func New(opts *Options) *Server {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if opts.Scenarios == nil {
+		opts.Scenarios = DefaultScenarios
+	}
+	if opts.PlayTTL == 0 {
+		opts.PlayTTL = time.Hour
+	}
+	if opts.PerPage == 0 {
+		opts.PerPage = 10
+	}
+	if opts.Check == nil {
+		opts.Check = func(*types.Play) bool { return true }
+	}
+	if opts.Exec == nil {
+		opts.Exec = DefaultExec
+	}
+	if opts.Dial == nil {
+		opts.Dial = DefaultDial
+	}
+
+	s := &Server{
+		opts:    opts,
+		mu:      &sync.Mutex{},
+		users:   map[string]*user{},
+		tokens:  map[string]string{},
+		pending: map[string]string{},
+		plays:   map[string]*play{},
+		shells:  map[string]*shell{},
+	}
+	s.Server = httptest.NewServer(s.routes())
+	return s
+}
+
+// The host:port of the server, for use as ClientOptions.Url
+func (s *Server) Host() string {
+	return strings.TrimPrefix(s.URL, "http://")
+}
+
+// Move the server's clock forwards, plays that boot or expire in the
+// meantime are updated straight away
+func (s *Server) Advance(d time.Duration) {
+	s.mu.Lock()
+	s.offset += d
+	s.refreshAll()
+	s.mu.Unlock()
+
+	s.notifyExpired()
+}
+
+func (s *Server) now() time.Time {
+	return time.Now().Add(s.offset)
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /auth", s.createUser)
+	mux.HandleFunc("POST /auth/login", s.login)
+	mux.HandleFunc("POST /auth/mfa/verify", s.verifyMFA)
+	mux.HandleFunc("GET /auth/me", s.authed(s.me))
+	mux.HandleFunc("GET /auth/tokens", s.authed(s.getApiTokens))
+	mux.HandleFunc("POST /auth/tokens", s.authed(s.createApiToken))
+	mux.HandleFunc("DELETE /auth/tokens", s.authed(s.deleteApiToken))
+	mux.HandleFunc("POST /auth/password/confirm", s.authed(s.confirmPassword))
+	mux.HandleFunc("POST /auth/password/update", s.authed(s.updatePassword))
+	mux.HandleFunc("POST /auth/mfa/configure", s.authed(s.configureMFA))
+	mux.HandleFunc("DELETE /auth/mfa", s.authed(s.removeMFA))
+	mux.HandleFunc("POST /auth/logout", s.authed(s.logout))
+	mux.HandleFunc("DELETE /auth/account", s.authed(s.deleteAccount))
+
+	mux.HandleFunc("GET /scenarios", s.getScenarios)
+	mux.HandleFunc("GET /scenarios/{name}", s.findScenario)
+
+	mux.HandleFunc("POST /plays", s.authed(s.startPlay))
+	mux.HandleFunc("GET /plays", s.authed(s.getPlays))
+	mux.HandleFunc("POST /plays/check", s.authed(s.checkPlay))
+	mux.HandleFunc("POST /plays/cancel", s.authed(s.cancelPlay))
+	mux.HandleFunc("GET /plays/active", s.authed(s.getActivePlay))
+	mux.HandleFunc("POST /plays/{id}", s.authed(s.getPlay))
+	mux.HandleFunc("GET /plays/{id}/shell", s.authed(s.shell))
+	mux.HandleFunc("GET /plays/{id}/watch", s.authed(s.spectate))
+	mux.HandleFunc("GET /plays/{id}/forward", s.authed(s.forward))
+	mux.HandleFunc("GET /plays/{id}/terminals", s.authed(s.getTerminals))
+	mux.HandleFunc("DELETE /plays/{id}/terminals/{terminal}", s.authed(s.closeTerminal))
+	mux.HandleFunc("GET /plays/{id}/spectators", s.authed(s.getSpectators))
+	mux.HandleFunc("POST /plays/{id}/spectators", s.authed(s.addSpectator))
+	mux.HandleFunc("DELETE /plays/{id}/spectators", s.authed(s.removeSpectator))
+	mux.HandleFunc("POST /plays/{id}/exec", s.authed(s.exec))
+	mux.HandleFunc("PUT /plays/{id}/files", s.authed(s.putFile))
+	mux.HandleFunc("GET /plays/{id}/files", s.authed(s.getFile))
+	mux.HandleFunc("PUT /plays/{id}/archive", s.authed(s.putArchive))
+	mux.HandleFunc("GET /plays/{id}/archive", s.authed(s.getArchive))
+
+	return mux
+}
+
+type authedHandler func(w http.ResponseWriter, r *http.Request, u *user, token string)
+
+func (s *Server) authed(h authedHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			writeError(w, http.StatusUnauthorized, "unauthenticated")
+			return
+		}
+
+		s.mu.Lock()
+		u, ok := s.users[s.tokens[token]]
+		s.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "unauthenticated")
+			return
+		}
+
+		// Handlers look plays up while holding the lock, so any that expired
+		// along the way have their shells finished here
+		defer s.notifyExpired()
+		h(w, r, u, token)
+	}
+}
+
+// Returns the user for the request if it is authenticated
+func (s *Server) optionalUser(r *http.Request) *user {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.users[s.tokens[token]]
+}
+
+func readJSON(r *http.Request, v any) error {
+	if r.Body == nil {
+		return errors.New("missing body")
+	}
+	return json.NewDecoder(r.Body).Decode(v)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"message": msg})
+}
+
+func writeValidationError(w http.ResponseWriter, field, msg string) {
+	writeJSON(w, http.StatusUnprocessableEntity, map[string]any{
+		"message": "Request failed validation",
+		"errors": map[string]string{
+			field: msg,
+		},
+	})
+}
+
+func randomString(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func newID() string {
+	return uuid.NewString()
+}
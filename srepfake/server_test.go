@@ -0,0 +1,383 @@
+package srepfake
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/srepio/sdk/client"
+	"github.com/srepio/sdk/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newClient(s *Server, token string) *client.Client {
+	return client.NewClient(&client.ClientOptions{
+		Url:    s.Host(),
+		Scheme: "http",
+		Token:  token,
+	})
+}
+
+func TestAuthFlowWithMFA(t *testing.T) {
+	s := New(nil)
+	defer s.Close()
+	ctx := context.Background()
+	c := newClient(s, "")
+
+	_, err := c.CreateUser(ctx, &client.CreateUserRequest{
+		Name:     "Bongo",
+		Email:    "bongo@srep.io",
+		Password: "hunter2hunter2",
+	})
+	require.Nil(t, err)
+
+	_, err = c.CreateUser(ctx, &client.CreateUserRequest{
+		Name:     "Bongo",
+		Email:    "bongo@srep.io",
+		Password: "hunter2hunter2",
+	})
+	assert.Error(t, err)
+
+	_, err = c.Login(ctx, &client.LoginRequest{Email: "bongo@srep.io", Password: "wrong"})
+	assert.Error(t, err)
+
+	login, err := c.Login(ctx, &client.LoginRequest{Email: "bongo@srep.io", Password: "hunter2hunter2"})
+	require.Nil(t, err)
+	require.NotEmpty(t, login.Token)
+	assert.False(t, login.Details.MFAEnabled)
+
+	c.Options.Token = login.Token
+	mfa, err := c.ConfigureMFA(ctx, &client.ConfigureMFARequest{Provider: types.TOTP})
+	require.Nil(t, err)
+
+	_, err = c.Logout(ctx, &client.LogoutRequest{})
+	require.Nil(t, err)
+	_, err = c.Me(ctx, &client.MeRequest{})
+	assert.Error(t, err)
+
+	login, err = c.Login(ctx, &client.LoginRequest{Email: "bongo@srep.io", Password: "hunter2hunter2"})
+	require.Nil(t, err)
+	assert.True(t, login.MFARequired)
+	assert.Empty(t, login.Token)
+
+	_, err = c.VerifyMFA(ctx, &client.VerifyMFARequest{AuthenticationID: login.AuthenticationID, Code: "000000"})
+	assert.Error(t, err)
+
+	code, err := TOTP(mfa.Data.Secret, time.Now())
+	require.Nil(t, err)
+	verified, err := c.VerifyMFA(ctx, &client.VerifyMFARequest{AuthenticationID: login.AuthenticationID, Code: code})
+	require.Nil(t, err)
+
+	c.Options.Token = verified.Token
+	me, err := c.Me(ctx, &client.MeRequest{})
+	require.Nil(t, err)
+	assert.Equal(t, "bongo@srep.io", me.User.Email)
+	assert.True(t, me.Details.MFAEnabled)
+}
+
+func TestApiTokens(t *testing.T) {
+	s := New(nil)
+	defer s.Close()
+	ctx := context.Background()
+	_, token := s.NewUser("Bongo", "bongo@srep.io", "hunter2hunter2")
+	c := newClient(s, token)
+
+	created, err := c.CreateApiToken(ctx, &client.CreateApiTokenRequest{Name: "ci"})
+	require.Nil(t, err)
+
+	tokens, err := c.GetApiTokens(ctx, &client.GetApiTokensRequest{})
+	require.Nil(t, err)
+	require.Len(t, tokens.Tokens, 1)
+	assert.Equal(t, "ci", tokens.Tokens[0].Name)
+
+	_, err = newClient(s, created.Token).Me(ctx, &client.MeRequest{})
+	assert.Nil(t, err)
+
+	_, err = c.DeleteApiToken(ctx, &client.DeleteApiTokenRequest{Name: "ci"})
+	require.Nil(t, err)
+	_, err = newClient(s, created.Token).Me(ctx, &client.MeRequest{})
+	assert.Error(t, err)
+}
+
+func TestPlayLifecycle(t *testing.T) {
+	s := New(&Options{BootTime: time.Minute, PlayTTL: time.Hour})
+	defer s.Close()
+	ctx := context.Background()
+	_, token := s.NewUser("Bongo", "bongo@srep.io", "hunter2hunter2")
+	c := newClient(s, token)
+
+	_, err := c.StartPlay(ctx, &client.StartPlayRequest{Scenario: "missing"})
+	assert.Error(t, err)
+
+	started, err := c.StartPlay(ctx, &client.StartPlayRequest{Scenario: "mango"})
+	require.Nil(t, err)
+	assert.Equal(t, types.PlayPending, started.Play.Status)
+
+	_, err = c.StartPlay(ctx, &client.StartPlayRequest{Scenario: "mango"})
+	assert.Error(t, err)
+
+	stdin, _ := pipe(t)
+	_, stdout := pipe(t)
+	err = c.GetShell(ctx, &client.GetShellRequest{ID: started.Play.ID}, stdin, stdout, nil)
+	assert.ErrorIs(t, err, client.ErrTooEarly)
+
+	s.Advance(time.Minute)
+	active, err := c.GetActivePlay(ctx, &client.GetActivePlayRequest{})
+	require.Nil(t, err)
+	assert.Equal(t, types.PlayRunning, active.Play.Status)
+
+	checked, err := c.CheckPlay(ctx, &client.CheckPlayRequest{ID: started.Play.ID})
+	require.Nil(t, err)
+	assert.True(t, checked.Passed)
+
+	play, err := c.GetPlay(ctx, &client.GetPlayRequest{ID: started.Play.ID})
+	require.Nil(t, err)
+	assert.Equal(t, types.PlayCompleted, play.Play.Status)
+	assert.NotNil(t, play.Play.FinishedAt)
+
+	second, err := c.StartPlay(ctx, &client.StartPlayRequest{Scenario: "mango"})
+	require.Nil(t, err)
+	s.Advance(2 * time.Hour)
+	play, err = c.GetPlay(ctx, &client.GetPlayRequest{ID: second.Play.ID})
+	require.Nil(t, err)
+	assert.Equal(t, types.PlayExpired, play.Play.Status)
+
+	plays, err := c.GetPlays(ctx, &client.GetPlaysRequest{})
+	require.Nil(t, err)
+	assert.Len(t, plays.Plays, 2)
+}
+
+func TestExec(t *testing.T) {
+	s := New(&Options{BootTime: time.Minute})
+	defer s.Close()
+	ctx := context.Background()
+	_, token := s.NewUser("Bongo", "bongo@srep.io", "hunter2hunter2")
+	c := newClient(s, token)
+
+	started, err := c.StartPlay(ctx, &client.StartPlayRequest{Scenario: "mango"})
+	require.Nil(t, err)
+	_, err = c.Exec(ctx, &client.ExecRequest{ID: started.Play.ID, Command: "true"})
+	assert.Error(t, err)
+	s.Advance(time.Minute)
+
+	out, err := c.Exec(ctx, &client.ExecRequest{ID: started.Play.ID, Command: "echo web-7d9f Running"})
+	require.Nil(t, err)
+	assert.Equal(t, "web-7d9f Running\n", string(out.Stdout))
+	assert.Nil(t, out.Err())
+
+	out, err = c.Exec(ctx, &client.ExecRequest{ID: started.Play.ID, Command: "cat", Stdin: []byte{0xff, 0x00}})
+	require.Nil(t, err)
+	assert.Equal(t, []byte{0xff, 0x00}, out.Stdout)
+
+	out, err = c.Exec(ctx, &client.ExecRequest{ID: started.Play.ID, Command: "kubectl get pods"})
+	require.Nil(t, err)
+	assert.Equal(t, 127, out.ExitCode)
+	assert.Equal(t, "sh: kubectl: command not found\n", string(out.Stderr))
+	var exit *client.ExitError
+	require.ErrorAs(t, out.Err(), &exit)
+	assert.Equal(t, 127, exit.Code)
+
+	play, err := c.GetPlay(ctx, &client.GetPlayRequest{ID: started.Play.ID})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"echo web-7d9f Running", "cat", "kubectl get pods"}, play.History)
+}
+
+func TestScenarioHistoryIsPaginated(t *testing.T) {
+	s := New(&Options{PerPage: 2})
+	defer s.Close()
+	ctx := context.Background()
+	_, token := s.NewUser("Bongo", "bongo@srep.io", "hunter2hunter2")
+	c := newClient(s, token)
+
+	for i := 0; i < 3; i++ {
+		started, err := c.StartPlay(ctx, &client.StartPlayRequest{Scenario: "mango"})
+		require.Nil(t, err)
+		_, err = c.CancelPlay(ctx, &client.CancelPlayRequest{ID: started.Play.ID})
+		require.Nil(t, err)
+	}
+
+	found, err := c.FindScenario(ctx, &client.FindScenarioRequest{Scenario: "mango", Page: 2})
+	require.Nil(t, err)
+	assert.True(t, *found.Scenario.Played)
+	assert.Equal(t, 3, found.History.Count)
+	assert.Equal(t, 2, found.History.TotalPages)
+	assert.Len(t, found.History.Data, 1)
+
+	scenarios, err := c.GetScenarios(ctx, &client.GetScenariosRequest{})
+	require.Nil(t, err)
+	assert.Len(t, *scenarios.Scenarios, len(DefaultScenarios))
+}
+
+func TestEchoShell(t *testing.T) {
+	s := New(nil)
+	defer s.Close()
+	ctx := context.Background()
+	_, token := s.NewUser("Bongo", "bongo@srep.io", "hunter2hunter2")
+	c := newClient(s, token)
+
+	started, err := c.StartPlay(ctx, &client.StartPlayRequest{Scenario: "mango"})
+	require.Nil(t, err)
+
+	stdinR, stdinW := pipe(t)
+	stdoutR, stdoutW := pipe(t)
+
+	plays := make(chan *types.Play, 1)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- c.GetShell(ctx, &client.GetShellRequest{ID: started.Play.ID}, stdinR, stdoutW, &client.ShellOptions{
+			OnActivePlay: func(play *types.Play) {
+				plays <- play
+			},
+		})
+	}()
+
+	select {
+	case play := <-plays:
+		assert.Equal(t, started.Play.ID, play.ID)
+	case <-time.After(time.Second * 5):
+		t.Fatal("active play not sent")
+	}
+
+	out := bufio.NewReader(stdoutR)
+	stdinW.Write([]byte("kubectl get pods\n"))
+	line, err := out.ReadString('\n')
+	require.Nil(t, err)
+	assert.True(t, strings.HasSuffix(line, "kubectl get pods\n"))
+
+	_, err = c.CancelPlay(ctx, &client.CancelPlayRequest{ID: started.Play.ID})
+	require.Nil(t, err)
+
+	select {
+	case err := <-errs:
+		assert.Nil(t, err)
+	case <-time.After(time.Second * 5):
+		t.Fatal("shell did not finish with the play")
+	}
+
+	play, err := c.GetPlay(ctx, &client.GetPlayRequest{ID: started.Play.ID})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"kubectl get pods"}, play.History)
+}
+
+func TestTerminalsEndWithThePlay(t *testing.T) {
+	s := New(nil)
+	defer s.Close()
+	ctx := context.Background()
+	_, token := s.NewUser("Bongo", "bongo@srep.io", "hunter2hunter2")
+	c := newClient(s, token)
+
+	started, err := c.StartPlay(ctx, &client.StartPlayRequest{Scenario: "mango"})
+	require.Nil(t, err)
+	terminals := func() []*types.Terminal {
+		resp, err := c.GetTerminals(ctx, &client.GetTerminalsRequest{ID: started.Play.ID})
+		require.Nil(t, err)
+		return resp.Terminals
+	}
+	assert.Empty(t, terminals())
+
+	sess, err := c.OpenShell(ctx, &client.GetShellRequest{ID: started.Play.ID, Rows: 24, Cols: 80, Terminal: "logs"}, nil)
+	require.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		list := terminals()
+		return len(list) == 1 && list[0].ID == "logs" && list[0].Attached == 1 && list[0].Rows == 24
+	}, time.Second, time.Millisecond*10)
+
+	_, err = c.CancelPlay(ctx, &client.CancelPlayRequest{ID: started.Play.ID})
+	require.Nil(t, err)
+	select {
+	case <-sess.Done():
+	case <-time.After(time.Second * 5):
+		t.Fatal("shell did not finish with the play")
+	}
+	assert.Empty(t, terminals())
+
+	_, err = c.CloseTerminal(ctx, &client.CloseTerminalRequest{ID: started.Play.ID, Terminal: "logs"})
+	assert.NotNil(t, err)
+}
+
+func TestSpectatorsCantType(t *testing.T) {
+	s := New(nil)
+	defer s.Close()
+	ctx := context.Background()
+	_, token := s.NewUser("Bongo", "bongo@srep.io", "hunter2hunter2")
+	_, teacher := s.NewUser("Teacher", "teacher@srep.io", "hunter2hunter2")
+	c := newClient(s, token)
+
+	started, err := c.StartPlay(ctx, &client.StartPlayRequest{Scenario: "mango"})
+	require.Nil(t, err)
+	_, err = c.AddSpectator(ctx, &client.AddSpectatorRequest{ID: started.Play.ID, Email: "teacher@srep.io"})
+	require.Nil(t, err)
+
+	header := http.Header{"Authorization": {"Bearer " + teacher}}
+	ws, _, err := websocket.DefaultDialer.Dial("ws://"+s.Host()+"/plays/"+started.Play.ID+"/watch", header)
+	require.Nil(t, err)
+	defer ws.Close()
+
+	input, err := types.EncodeEvent(&types.InputPayload{Data: []byte("rm -rf /\n")})
+	require.Nil(t, err)
+	require.Nil(t, ws.WriteJSON(input))
+
+	ws.SetReadDeadline(time.Now().Add(time.Second * 5))
+	for {
+		ev := &types.SocketEvent{}
+		require.Nil(t, ws.ReadJSON(ev))
+		if ev.Type != types.Rejected {
+			continue
+		}
+		rejected, err := types.DecodeAs[types.RejectedPayload](ev)
+		require.Nil(t, err)
+		assert.Equal(t, types.Input, rejected.Event)
+		break
+	}
+
+	play, err := c.GetPlay(ctx, &client.GetPlayRequest{ID: started.Play.ID})
+	require.Nil(t, err)
+	assert.Empty(t, play.History)
+}
+
+func pipe(t *testing.T) (*os.File, *os.File) {
+	r, w, err := os.Pipe()
+	require.Nil(t, err)
+	t.Cleanup(func() {
+		r.Close()
+		w.Close()
+	})
+	return r, w
+}
+
+func TestShellsFinishWhenALookupExpiresThePlay(t *testing.T) {
+	s := New(nil)
+	defer s.Close()
+	ctx := context.Background()
+	_, token := s.NewUser("Bongo", "bongo@srep.io", "hunter2hunter2")
+	c := newClient(s, token)
+
+	started, err := c.StartPlay(ctx, &client.StartPlayRequest{Scenario: "mango"})
+	require.Nil(t, err)
+
+	// A shell without a watcher, and a clock moved without Advance, so only
+	// the lookup below can notice the expiry
+	sh := newShell()
+	s.mu.Lock()
+	s.shells[started.Play.ID] = sh
+	s.offset += 2 * time.Hour
+	s.mu.Unlock()
+
+	play, err := c.GetPlay(ctx, &client.GetPlayRequest{ID: started.Play.ID})
+	require.Nil(t, err)
+	assert.Equal(t, types.PlayExpired, play.Play.Status)
+
+	sh.mu.Lock()
+	assert.True(t, sh.closed)
+	sh.mu.Unlock()
+	s.mu.Lock()
+	assert.NotContains(t, s.shells, started.Play.ID)
+	assert.Empty(t, s.expired)
+	s.mu.Unlock()
+}
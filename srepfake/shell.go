@@ -0,0 +1,482 @@
+package srepfake
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/srepio/sdk/types"
+)
+
+var (
+	upgrader = websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}
+	watchInterval = time.Millisecond * 50
+	writeWait     = time.Second
+
+	finishReasons = map[types.PlayStatus]string{
+		types.PlayCompleted: "check passed",
+		types.PlayFailed:    "check failed",
+		types.PlayCancelled: "cancelled",
+		types.PlayExpired:   "expired",
+	}
+)
+
+type conn struct {
+	ws *websocket.Conn
+	mu *sync.Mutex
+	// Set once the connection has stopped responding
+	stalled atomic.Bool
+	// Output is base64 encoded for clients that negotiated it
+	base64 bool
+	// The terminal the connection was opened for, events for any other
+	// terminal it attaches to are tagged with the terminal's ID
+	terminal string
+	// The user watching when the connection is a spectator's
+	spectator string
+}
+
+func newConn(ws *websocket.Conn) *conn {
+	c := &conn{
+		ws:     ws,
+		mu:     &sync.Mutex{},
+		base64: ws.Subprotocol() == types.Base64Protocol,
+	}
+	ws.SetPingHandler(func(data string) error {
+		if c.stalled.Load() {
+			return nil
+		}
+		return ws.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(writeWait))
+	})
+	return c
+}
+
+func (c *conn) write(ev *types.SocketEvent) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stalled.Load() {
+		return nil
+	}
+	if c.base64 && ev.Type == types.Output {
+		encoded := *ev
+		encoded.EncodeBase64()
+		ev = &encoded
+	}
+
+	c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+	return c.ws.WriteJSON(ev)
+}
+
+func (c *conn) send(p types.Payload) error {
+	ev, err := types.EncodeEvent(p)
+	if err != nil {
+		return err
+	}
+	return c.write(ev)
+}
+
+// Write an event for a terminal the connection is attached to
+func (c *conn) writeFor(terminal string, ev *types.SocketEvent) error {
+	if terminal != c.terminal {
+		tagged := *ev
+		tagged.Terminal = terminal
+		ev = &tagged
+	}
+	return c.write(ev)
+}
+
+func (c *conn) sendFor(terminal string, p types.Payload) error {
+	ev, err := types.EncodeEvent(p)
+	if err != nil {
+		return err
+	}
+	return c.writeFor(terminal, ev)
+}
+
+func (c *conn) close(code int, text string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(writeWait))
+	c.ws.Close()
+}
+
+// The echo shells of a play. Every connection to the play is told when it
+// finishes, while terminal output only goes to the connections attached to
+// the terminal.
+type shell struct {
+	mu        *sync.Mutex
+	conns     map[*conn]struct{}
+	terminals map[string]*terminal
+	// Spectator connections and the terminal each is watching
+	watchers map[*conn]string
+	closed   bool
+}
+
+type terminal struct {
+	id      string
+	rows    uint16
+	cols    uint16
+	created int64
+	conns   map[*conn]struct{}
+}
+
+func newShell() *shell {
+	return &shell{
+		mu:        &sync.Mutex{},
+		conns:     map[*conn]struct{}{},
+		terminals: map[string]*terminal{},
+		watchers:  map[*conn]string{},
+	}
+}
+
+func (sh *shell) add(c *conn) bool {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if sh.closed {
+		return false
+	}
+	sh.conns[c] = struct{}{}
+	return true
+}
+
+func (sh *shell) remove(c *conn) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	delete(sh.conns, c)
+	delete(sh.watchers, c)
+	for _, t := range sh.terminals {
+		delete(t.conns, c)
+	}
+}
+
+// Attach the connection to a terminal, creating it with the size when it
+// doesn't exist
+func (sh *shell) attach(c *conn, id string, rows, cols uint16, now int64) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	t, ok := sh.terminals[id]
+	if !ok {
+		t = &terminal{id: id, rows: rows, cols: cols, created: now, conns: map[*conn]struct{}{}}
+		sh.terminals[id] = t
+		if ev, err := types.EncodeEvent(&types.ResizePayload{Rows: rows, Cols: cols}); err == nil {
+			sh.toWatchers(id, ev)
+		}
+	}
+	t.conns[c] = struct{}{}
+}
+
+func (sh *shell) detach(c *conn, id string) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if t, ok := sh.terminals[id]; ok {
+		delete(t.conns, c)
+	}
+}
+
+func (sh *shell) attached(c *conn, id string) bool {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	t, ok := sh.terminals[id]
+	if !ok {
+		return false
+	}
+	_, ok = t.conns[c]
+	return ok
+}
+
+func (sh *shell) broadcast(id string, ev *types.SocketEvent) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if t, ok := sh.terminals[id]; ok {
+		for c := range t.conns {
+			c.writeFor(id, ev)
+		}
+		sh.toWatchers(id, ev)
+	}
+}
+
+// Must be called while holding the shell lock
+func (sh *shell) toWatchers(id string, ev *types.SocketEvent) {
+	for c, watching := range sh.watchers {
+		if watching == id {
+			c.write(ev)
+		}
+	}
+}
+
+// Spectators follow the player's terminal size
+func (sh *shell) resize(id string, rows, cols uint16) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	t, ok := sh.terminals[id]
+	if !ok || (t.rows == rows && t.cols == cols) {
+		return
+	}
+	t.rows, t.cols = rows, cols
+	if ev, err := types.EncodeEvent(&types.ResizePayload{Rows: rows, Cols: cols}); err == nil {
+		sh.toWatchers(id, ev)
+	}
+}
+
+// The last size sent by a client for the terminal
+func (sh *shell) size(id string) (uint16, uint16) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if t, ok := sh.terminals[id]; ok {
+		return t.rows, t.cols
+	}
+	return 0, 0
+}
+
+func (sh *shell) finish(p *types.Play) {
+	ev, _ := types.EncodeEvent(&types.PlayFinishedPayload{
+		Status: p.Status,
+		Reason: finishReasons[p.Status],
+	})
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	sh.closed = true
+	sh.terminals = map[string]*terminal{}
+	for c := range sh.conns {
+		c.write(ev)
+		c.close(websocket.CloseNormalClosure, string(p.Status))
+	}
+	sh.conns = map[*conn]struct{}{}
+}
+
+// Abruptly close every connection to the play's shell without a close frame,
+// as if the network had failed
+func (s *Server) DropConnections(playID string) {
+	s.mu.Lock()
+	sh, ok := s.shells[playID]
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	for c := range sh.conns {
+		c.ws.NetConn().Close()
+	}
+}
+
+// Stop responding on every connection to the play's shell while leaving them
+// open, as if the connection had gone half-open
+func (s *Server) StallConnections(playID string) {
+	s.mu.Lock()
+	sh, ok := s.shells[playID]
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	for c := range sh.conns {
+		c.stalled.Store(true)
+	}
+}
+
+// The last size sent by a client to the play's default terminal
+func (s *Server) ShellSize(playID string) (rows, cols uint16) {
+	return s.TerminalSize(playID, types.DefaultTerminal)
+}
+
+// The last size sent by a client to one of the play's terminals
+func (s *Server) TerminalSize(playID, terminal string) (rows, cols uint16) {
+	s.mu.Lock()
+	sh, ok := s.shells[playID]
+	s.mu.Unlock()
+	if !ok {
+		return 0, 0
+	}
+	return sh.size(terminal)
+}
+
+// Find the play a socket is being opened for and its shell, writing the
+// error response when the play isn't running
+func (s *Server) playSockets(w http.ResponseWriter, r *http.Request, u *user, find func(u *user, id string) (*play, bool)) (*play, *shell, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := find(u, r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "play not found")
+		return nil, nil, false
+	}
+	switch p.Status {
+	case types.PlayPending:
+		writeError(w, http.StatusTooEarly, "play is still starting")
+		return nil, nil, false
+	case types.PlayRunning:
+	default:
+		writeError(w, http.StatusGone, "play has finished")
+		return nil, nil, false
+	}
+	sh, ok := s.shells[p.ID]
+	if !ok {
+		sh = newShell()
+		s.shells[p.ID] = sh
+	}
+	return p, sh, true
+}
+
+func (s *Server) shell(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	id, ok := terminalParam(w, r)
+	if !ok {
+		return
+	}
+	p, sh, ok := s.playSockets(w, r, u, s.userPlay)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	snapshot := p.Play
+	now := s.now().Unix()
+	s.mu.Unlock()
+
+	up := upgrader
+	if !s.opts.TextOnly {
+		up.Subprotocols = []string{types.Base64Protocol}
+	}
+	ws, err := up.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := newConn(ws)
+	c.terminal = id
+	if !sh.add(c) {
+		c.close(websocket.CloseNormalClosure, "play has finished")
+		return
+	}
+	defer sh.remove(c)
+	defer ws.Close()
+	sh.attach(c, id, 0, 0, now)
+
+	c.send(&types.ActivePlayPayload{Play: snapshot})
+
+	done := make(chan struct{})
+	defer close(done)
+	go s.watch(p, done)
+	if s.opts.PingInterval > 0 {
+		go ping(c, s.opts.PingInterval, done)
+	}
+
+	for {
+		ev := &types.SocketEvent{}
+		if err := ws.ReadJSON(ev); err != nil {
+			return
+		}
+		if c.stalled.Load() {
+			continue
+		}
+		payload, err := types.DecodeEvent(ev)
+		if err != nil {
+			continue
+		}
+		target := c.terminal
+		if ev.Terminal != "" {
+			target = ev.Terminal
+		}
+		if !terminalID.MatchString(target) {
+			continue
+		}
+
+		switch in := payload.(type) {
+		case *types.PingPayload:
+			c.sendFor(target, &types.PongPayload{Data: in.Data})
+		case *types.ResizePayload:
+			if sh.attached(c, target) {
+				sh.resize(target, in.Rows, in.Cols)
+			}
+		case *types.InputPayload:
+			if sh.attached(c, target) {
+				s.record(p, string(in.Data))
+				out, _ := types.EncodeEvent(&types.OutputPayload{Data: in.Data})
+				sh.broadcast(target, out)
+			}
+		case *types.TerminalOpenPayload:
+			s.mu.Lock()
+			now := s.now().Unix()
+			s.mu.Unlock()
+			sh.attach(c, target, in.Rows, in.Cols, now)
+		case *types.TerminalDetachPayload:
+			sh.detach(c, target)
+		case *types.TerminalClosePayload:
+			sh.closeTerminal(target, "closed by client")
+		}
+	}
+}
+
+// Record completed input lines in the play's history
+func (s *Server) record(p *play, input string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, b := range []byte(input) {
+		switch b {
+		case '\r', '\n':
+			if len(p.line) > 0 {
+				p.history = append(p.history, string(p.line))
+				p.line = nil
+			}
+		default:
+			p.line = append(p.line, b)
+		}
+	}
+}
+
+// Finish the shell when the play expires while it is connected
+func (s *Server) watch(p *play, done <-chan struct{}) {
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			s.refresh(p)
+			finished := !p.active()
+			s.mu.Unlock()
+			s.notifyExpired()
+			if finished {
+				s.notify([]*play{p})
+				return
+			}
+		}
+	}
+}
+
+func ping(c *conn, interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := c.send(&types.PingPayload{}); err != nil {
+				return
+			}
+		}
+	}
+}
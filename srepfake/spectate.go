@@ -0,0 +1,199 @@
+package srepfake
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/srepio/sdk/types"
+)
+
+// Get a play the user owns or has been added to as a spectator, refreshing
+// its status
+func (s *Server) viewablePlay(u *user, id string) (*play, bool) {
+	p, ok := s.plays[id]
+	if !ok {
+		return nil, false
+	}
+	if _, watching := p.spectators[u.ID]; p.UserID != u.ID && !watching {
+		return nil, false
+	}
+	s.refresh(p)
+	return p, true
+}
+
+// Start sending a terminal's output and resizes to a spectator
+func (sh *shell) watch(c *conn, id string) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	sh.watchers[c] = id
+}
+
+// Disconnect a user that is no longer allowed to watch
+func (sh *shell) dropSpectator(userID string) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	for c := range sh.watchers {
+		if c.spectator == userID {
+			c.close(websocket.CloseNormalClosure, "no longer a spectator")
+		}
+	}
+}
+
+func (s *Server) spectate(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	id, ok := terminalParam(w, r)
+	if !ok {
+		return
+	}
+	p, sh, ok := s.playSockets(w, r, u, s.viewablePlay)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	snapshot := p.Play
+	s.mu.Unlock()
+
+	up := upgrader
+	if !s.opts.TextOnly {
+		up.Subprotocols = []string{types.Base64Protocol}
+	}
+	ws, err := up.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := newConn(ws)
+	c.terminal = id
+	c.spectator = u.ID
+	if !sh.add(c) {
+		c.close(websocket.CloseNormalClosure, "play has finished")
+		return
+	}
+	defer sh.remove(c)
+	defer ws.Close()
+
+	c.send(&types.ActivePlayPayload{Play: snapshot})
+	if rows, cols := sh.size(id); rows != 0 && cols != 0 {
+		c.send(&types.ResizePayload{Rows: rows, Cols: cols})
+	}
+	sh.watch(c, id)
+
+	done := make(chan struct{})
+	defer close(done)
+	go s.watch(p, done)
+	if s.opts.PingInterval > 0 {
+		go ping(c, s.opts.PingInterval, done)
+	}
+
+	for {
+		ev := &types.SocketEvent{}
+		if err := ws.ReadJSON(ev); err != nil {
+			return
+		}
+		payload, err := types.DecodeEvent(ev)
+		if err != nil {
+			continue
+		}
+
+		switch in := payload.(type) {
+		case *types.PingPayload:
+			c.send(&types.PongPayload{Data: in.Data})
+		case *types.PongPayload:
+		default:
+			c.send(&types.RejectedPayload{Event: ev.Type, Reason: "spectators are read-only"})
+		}
+	}
+}
+
+func (s *Server) getSpectators(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.userPlay(u, r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "play not found")
+		return
+	}
+	out := []types.User{}
+	for id := range p.spectators {
+		if spectator, ok := s.users[id]; ok {
+			out = append(out, spectator.User)
+		}
+	}
+	slices.SortFunc(out, func(a, b types.User) int {
+		return strings.Compare(a.Email, b.Email)
+	})
+	writeJSON(w, http.StatusOK, map[string]any{"spectators": out})
+}
+
+func (s *Server) addSpectator(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	req := struct {
+		Email string `json:"email"`
+	}{}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.userPlay(u, r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "play not found")
+		return
+	}
+	spectator := s.findUserByEmail(req.Email)
+	if spectator == nil {
+		writeError(w, http.StatusNotFound, "user not found")
+		return
+	}
+	if spectator.ID == u.ID {
+		writeValidationError(w, "email", "can't spectate your own play")
+		return
+	}
+	if p.spectators == nil {
+		p.spectators = map[string]struct{}{}
+	}
+	p.spectators[spectator.ID] = struct{}{}
+	writeJSON(w, http.StatusOK, map[string]any{"spectator": spectator.User})
+}
+
+func (s *Server) removeSpectator(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	req := struct {
+		Email string `json:"email"`
+	}{}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	p, ok := s.userPlay(u, r.PathValue("id"))
+	if !ok {
+		s.mu.Unlock()
+		writeError(w, http.StatusNotFound, "play not found")
+		return
+	}
+	spectator := s.findUserByEmail(req.Email)
+	if spectator == nil {
+		s.mu.Unlock()
+		writeError(w, http.StatusNotFound, "spectator not found")
+		return
+	}
+	if _, watching := p.spectators[spectator.ID]; !watching {
+		s.mu.Unlock()
+		writeError(w, http.StatusNotFound, "spectator not found")
+		return
+	}
+	delete(p.spectators, spectator.ID)
+	sh := s.shells[p.ID]
+	s.mu.Unlock()
+
+	if sh != nil {
+		sh.dropSpectator(spectator.ID)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{})
+}
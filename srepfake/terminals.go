@@ -0,0 +1,132 @@
+package srepfake
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+
+	"github.com/gorilla/websocket"
+	"github.com/srepio/sdk/types"
+)
+
+var (
+	terminalID = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+)
+
+// End a terminal and send a TerminalClose event to the connections attached
+// to it. Connections opened for it that aren't attached to any other
+// terminal are closed, as they have nothing left to carry.
+func (sh *shell) closeTerminal(id, reason string) bool {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	t, ok := sh.terminals[id]
+	if !ok {
+		return false
+	}
+	delete(sh.terminals, id)
+	for c := range t.conns {
+		c.sendFor(id, &types.TerminalClosePayload{Reason: reason})
+		if c.terminal == id && !sh.attachedElsewhere(c) {
+			c.close(websocket.CloseNormalClosure, "terminal closed")
+		}
+	}
+	for c, watching := range sh.watchers {
+		if watching == id {
+			c.send(&types.TerminalClosePayload{Reason: reason})
+			c.close(websocket.CloseNormalClosure, "terminal closed")
+		}
+	}
+	return true
+}
+
+func (sh *shell) attachedElsewhere(c *conn) bool {
+	for _, t := range sh.terminals {
+		if _, ok := t.conns[c]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// The play's terminals, oldest first
+func (sh *shell) list() []*types.Terminal {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	out := []*types.Terminal{}
+	for _, t := range sh.terminals {
+		watching := 0
+		for _, id := range sh.watchers {
+			if id == t.id {
+				watching++
+			}
+		}
+		out = append(out, &types.Terminal{
+			ID:         t.id,
+			Rows:       t.rows,
+			Cols:       t.cols,
+			Attached:   len(t.conns),
+			Spectators: watching,
+			CreatedAt:  t.created,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAt != out[j].CreatedAt {
+			return out[i].CreatedAt < out[j].CreatedAt
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// The terminal named in the request's query, writing the error response
+// when it isn't valid
+func terminalParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := r.URL.Query().Get("terminal")
+	if id == "" {
+		id = types.DefaultTerminal
+	}
+	if !terminalID.MatchString(id) {
+		writeValidationError(w, "terminal", "must be letters, digits, '.', '_' or '-'")
+		return "", false
+	}
+	return id, true
+}
+
+// The shell of a play owned by the user, nil when nothing has connected
+func (s *Server) userShell(w http.ResponseWriter, r *http.Request, u *user) (*shell, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.userPlay(u, r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "play not found")
+		return nil, false
+	}
+	return s.shells[p.ID], true
+}
+
+func (s *Server) getTerminals(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	sh, ok := s.userShell(w, r, u)
+	if !ok {
+		return
+	}
+	terminals := []*types.Terminal{}
+	if sh != nil {
+		terminals = sh.list()
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"terminals": terminals})
+}
+
+func (s *Server) closeTerminal(w http.ResponseWriter, r *http.Request, u *user, _ string) {
+	sh, ok := s.userShell(w, r, u)
+	if !ok {
+		return
+	}
+	if sh == nil || !sh.closeTerminal(r.PathValue("terminal"), "closed") {
+		writeError(w, http.StatusNotFound, "terminal not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{})
+}
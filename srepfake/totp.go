@@ -0,0 +1,58 @@
+package srepfake
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	totpPeriod = 30
+	totpDigits = 6
+)
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func newTOTPSecret() string {
+	b := make([]byte, 20)
+	rand.Read(b)
+	return b32.EncodeToString(b)
+}
+
+// Generate the RFC 6238 code for a secret returned by ConfigureMFA
+func TOTP(secret string, at time.Time) (string, error) {
+	key, err := b32.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(at.Unix()/totpPeriod))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, code%1000000), nil
+}
+
+// Accepts codes from the previous, current and next period to allow for skew
+func validTOTP(secret, code string, at time.Time) bool {
+	for _, skew := range []time.Duration{-totpPeriod, 0, totpPeriod} {
+		expected, err := TOTP(secret, at.Add(skew*time.Second))
+		if err != nil {
+			return false
+		}
+		if hmac.Equal([]byte(expected), []byte(code)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,221 @@
+package sshgw
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/srepio/sdk/client"
+)
+
+var (
+	errDirectories = errors.New("directories are not supported")
+	errRelative    = errors.New("paths must be absolute")
+)
+
+type scpCommand struct {
+	// Receiving files with -t, otherwise sending them with -f
+	sink bool
+	// The target is a directory, scp sets this when copying many files
+	dir   bool
+	paths []string
+}
+
+// Parse the command an scp client runs on the remote end, ok is false when
+// it isn't scp in sink or source mode
+func scpArgs(command string) (*scpCommand, bool) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 || fields[0] != "scp" {
+		return nil, false
+	}
+
+	cmd := &scpCommand{}
+	mode := false
+	flags := true
+	for _, f := range fields[1:] {
+		if flags && f == "--" {
+			flags = false
+			continue
+		}
+		if !flags || !strings.HasPrefix(f, "-") {
+			cmd.paths = append(cmd.paths, f)
+			continue
+		}
+		for _, c := range f[1:] {
+			switch c {
+			case 't':
+				cmd.sink, mode = true, true
+			case 'f':
+				mode = true
+			case 'd':
+				cmd.dir = true
+			case 'r':
+				// Rejected once the client sends a directory
+			}
+		}
+	}
+	return cmd, mode
+}
+
+// Run the remote end of the legacy scp protocol, copying single files with
+// UploadFile and DownloadFile. OpenSSH 9 and later use SFTP by default so
+// need -O to use it.
+func (s *session) scp(ctx context.Context, id string, cmd *scpCommand) int {
+	if len(cmd.paths) == 0 {
+		return s.fail(errors.New("scp: missing path"))
+	}
+	for _, p := range cmd.paths {
+		if !path.IsAbs(p) {
+			s.reject(errRelative)
+			return 1
+		}
+	}
+
+	r := bufio.NewReader(s.ch)
+	if cmd.sink {
+		return s.sink(ctx, id, r, cmd)
+	}
+	return s.source(ctx, id, r, cmd.paths)
+}
+
+// Receive files from the client into the play
+func (s *session) sink(ctx context.Context, id string, r *bufio.Reader, cmd *scpCommand) int {
+	target := cmd.paths[len(cmd.paths)-1]
+	s.ack()
+
+	for {
+		line, err := r.ReadString('\n')
+		if err == io.EOF && line == "" {
+			return 0
+		}
+		if err != nil {
+			return 1
+		}
+
+		switch line[0] {
+		case 'T':
+			// Modification times aren't kept
+			s.ack()
+		case 'C':
+			mode, size, name, err := parseFileHeader(line)
+			if err != nil {
+				s.reject(err)
+				return 1
+			}
+			dest := target
+			if cmd.dir || strings.HasSuffix(target, "/") {
+				dest = path.Join(target, name)
+			}
+			s.ack()
+
+			body := io.LimitReader(r, size)
+			_, err = s.srv.api.UploadFile(ctx, &client.UploadFileRequest{
+				ID:   id,
+				Path: dest,
+				Mode: mode,
+				Body: body,
+				Size: size,
+			})
+			// The rest of the file has to be consumed to stay in step with
+			// the client, followed by the null byte that ends it
+			io.Copy(io.Discard, body)
+			if b, rerr := r.ReadByte(); rerr != nil || b != 0 {
+				return 1
+			}
+			if err != nil {
+				s.reject(err)
+				return 1
+			}
+			s.ack()
+		case 'D', 'E':
+			s.reject(errDirectories)
+			return 1
+		default:
+			s.reject(fmt.Errorf("unexpected message %q", strings.TrimSpace(line)))
+			return 1
+		}
+	}
+}
+
+// Send files from the play to the client. The size has to be sent before
+// the content so each file is held in memory.
+func (s *session) source(ctx context.Context, id string, r *bufio.Reader, paths []string) int {
+	if !acked(r) {
+		return 1
+	}
+
+	for _, p := range paths {
+		buf := &bytes.Buffer{}
+		resp, err := s.srv.api.DownloadFile(ctx, &client.DownloadFileRequest{ID: id, Path: p}, buf)
+		if err != nil {
+			s.reject(err)
+			return 1
+		}
+		mode := resp.Mode.Perm()
+		if mode == 0 {
+			mode = 0644
+		}
+
+		fmt.Fprintf(s.ch, "C%04o %d %s\n", mode, buf.Len(), path.Base(p))
+		if !acked(r) {
+			return 1
+		}
+		s.ch.Write(buf.Bytes())
+		s.ack()
+		if !acked(r) {
+			return 1
+		}
+	}
+	return 0
+}
+
+func (s *session) ack() {
+	s.ch.Write([]byte{0})
+}
+
+// Report an error to the scp client, which prints it
+func (s *session) reject(err error) {
+	fmt.Fprintf(s.ch, "\x01scp: %v\n", err)
+}
+
+// Wait for the client to acknowledge the last message
+func acked(r *bufio.Reader) bool {
+	b, err := r.ReadByte()
+	if err != nil {
+		return false
+	}
+	if b != 0 {
+		// Warnings and errors are followed by a message
+		r.ReadString('\n')
+		return false
+	}
+	return true
+}
+
+// Parse a "C<mode> <size> <name>" file header
+func parseFileHeader(line string) (os.FileMode, int64, string, error) {
+	fields := strings.SplitN(strings.TrimSuffix(line[1:], "\n"), " ", 3)
+	if len(fields) != 3 {
+		return 0, 0, "", fmt.Errorf("malformed file header %q", strings.TrimSpace(line))
+	}
+	mode, err := strconv.ParseUint(fields[0], 8, 32)
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("malformed file mode %q", fields[0])
+	}
+	size, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil || size < 0 {
+		return 0, 0, "", fmt.Errorf("malformed file size %q", fields[1])
+	}
+	name := fields[2]
+	if name == "" || name == "." || name == ".." || strings.Contains(name, "/") {
+		return 0, 0, "", fmt.Errorf("invalid file name %q", name)
+	}
+	return os.FileMode(mode).Perm(), size, name, nil
+}
@@ -0,0 +1,242 @@
+// Package sshgw is an SSH server that bridges sessions to play shells, so
+// engineers can reach their plays with their own SSH clients.
+package sshgw
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"sync"
+
+	"github.com/srepio/sdk/client"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	ErrNoAuth       = errors.New("no way to authenticate clients was configured")
+	ErrServerClosed = errors.New("ssh gateway closed")
+	// Returned to exec clients that pipe in more than the exec API takes
+	ErrStdinTooLarge = fmt.Errorf("standard input is over the %d byte limit for commands", maxExecStdin)
+
+	playID = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+type Options struct {
+	// Identifies the gateway to clients, an ed25519 key is generated when
+	// nil so clients will see a new key on every start
+	HostKey ssh.Signer
+	// Clients are authenticated locally with any of these, at least one
+	// must be set unless NoClientAuth is
+	Password       func(conn ssh.ConnMetadata, password []byte) error
+	PublicKey      func(conn ssh.ConnMetadata, key ssh.PublicKey) error
+	AuthorizedKeys []ssh.PublicKey
+	NoClientAuth   bool
+	// Picks the play a connection uses. Defaults to the play named by the
+	// SSH user when it is a play ID and the active play otherwise.
+	Play func(ctx context.Context, conn ssh.ConnMetadata) (string, error)
+	// Copied for every shell that is opened
+	Shell *client.ShellOptions
+}
+
+// An SSH server that gives each session the shell of a play. Interactive
+// sessions open the play's shell, commands run through the exec API and
+// single files can be copied with the legacy scp protocol.
+type Server struct {
+	api    client.API
+	opts   *Options
+	config *ssh.ServerConfig
+
+	mu        *sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	closed    bool
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+func New(api client.API, opts *Options) (*Server, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	o := *opts
+	if o.Password == nil && o.PublicKey == nil && len(o.AuthorizedKeys) == 0 && !o.NoClientAuth {
+		return nil, ErrNoAuth
+	}
+
+	config := &ssh.ServerConfig{
+		NoClientAuth: o.NoClientAuth,
+	}
+	if o.Password != nil {
+		config.PasswordCallback = func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			return nil, o.Password(conn, password)
+		}
+	}
+	if o.PublicKey != nil || len(o.AuthorizedKeys) > 0 {
+		config.PublicKeyCallback = func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			return nil, o.authorize(conn, key)
+		}
+	}
+
+	if o.HostKey == nil {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		o.HostKey, err = ssh.NewSignerFromKey(key)
+		if err != nil {
+			return nil, err
+		}
+	}
+	config.AddHostKey(o.HostKey)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		api:       api,
+		opts:      &o,
+		config:    config,
+		mu:        &sync.Mutex{},
+		listeners: map[net.Listener]struct{}{},
+		conns:     map[net.Conn]struct{}{},
+		ctx:       ctx,
+		cancel:    cancel,
+	}, nil
+}
+
+func (o *Options) authorize(conn ssh.ConnMetadata, key ssh.PublicKey) error {
+	for _, k := range o.AuthorizedKeys {
+		if k.Type() == key.Type() && string(k.Marshal()) == string(key.Marshal()) {
+			return nil
+		}
+	}
+	if o.PublicKey != nil {
+		return o.PublicKey(conn, key)
+	}
+	return fmt.Errorf("unknown public key for %s", conn.User())
+}
+
+func (s *Server) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Accept connections until the server is closed, which returns
+// ErrServerClosed
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		ln.Close()
+		return ErrServerClosed
+	}
+	s.listeners[ln] = struct{}{}
+	s.mu.Unlock()
+	defer s.forget(ln)
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+// Stop listening and drop every connection along with its play shells
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	s.cancel()
+	for ln := range s.listeners {
+		ln.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	return nil
+}
+
+func (s *Server) forget(ln net.Listener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.listeners, ln)
+}
+
+func (s *Server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	return true
+}
+
+func (s *Server) untrack(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, conn)
+}
+
+func (s *Server) handle(conn net.Conn) {
+	if !s.track(conn) {
+		conn.Close()
+		return
+	}
+	defer s.untrack(conn)
+	defer conn.Close()
+
+	sc, chans, reqs, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		return
+	}
+	defer sc.Close()
+	go ssh.DiscardRequests(reqs)
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	for nc := range chans {
+		if nc.ChannelType() != "session" {
+			nc.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+		ch, requests, err := nc.Accept()
+		if err != nil {
+			continue
+		}
+		sess := &session{srv: s, meta: sc, ch: ch, mu: &sync.Mutex{}}
+		go sess.serve(ctx, requests)
+	}
+}
+
+// The play a connection uses
+func (s *Server) play(ctx context.Context, conn ssh.ConnMetadata) (string, error) {
+	if s.opts.Play != nil {
+		return s.opts.Play(ctx, conn)
+	}
+	if playID.MatchString(conn.User()) {
+		return conn.User(), nil
+	}
+	active, err := s.api.GetActivePlay(ctx, &client.GetActivePlayRequest{})
+	if err != nil {
+		return "", fmt.Errorf("finding the active play: %w", err)
+	}
+	if active.Play == nil {
+		return "", errors.New("there is no active play")
+	}
+	return active.Play.ID, nil
+}
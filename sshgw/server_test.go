@@ -0,0 +1,273 @@
+package sshgw
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srepio/sdk/client"
+	"github.com/srepio/sdk/srepfake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+type testGateway struct {
+	fake   *srepfake.Server
+	client *client.Client
+	playID string
+	addr   string
+}
+
+func prepareGateway(t *testing.T) *testGateway {
+	s := srepfake.New(nil)
+	t.Cleanup(s.Close)
+	_, token := s.NewUser("Bongo", "bongo@srep.io", "hunter2hunter2")
+	c := client.NewClient(&client.ClientOptions{
+		Url:    s.Host(),
+		Scheme: "http",
+		Token:  token,
+	})
+	started, err := c.StartPlay(context.Background(), &client.StartPlayRequest{Scenario: "mango"})
+	require.Nil(t, err)
+
+	gw, err := New(c, &Options{
+		Password: func(conn ssh.ConnMetadata, password []byte) error {
+			if string(password) != "letmein" {
+				return errors.New("wrong password")
+			}
+			return nil
+		},
+	})
+	require.Nil(t, err)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	go gw.Serve(ln)
+	t.Cleanup(func() { gw.Close() })
+
+	return &testGateway{fake: s, client: c, playID: started.Play.ID, addr: ln.Addr().String()}
+}
+
+func (tg *testGateway) dial(t *testing.T, password string) (*ssh.Client, error) {
+	return ssh.Dial("tcp", tg.addr, &ssh.ClientConfig{
+		User:            "bongo",
+		Auth:            []ssh.AuthMethod{ssh.Password(password)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         time.Second,
+	})
+}
+
+func (tg *testGateway) session(t *testing.T) *ssh.Session {
+	conn, err := tg.dial(t, "letmein")
+	require.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+	sess, err := conn.NewSession()
+	require.Nil(t, err)
+	return sess
+}
+
+func TestNeedsAuth(t *testing.T) {
+	_, err := New(nil, nil)
+	assert.ErrorIs(t, err, ErrNoAuth)
+}
+
+func TestWrongPassword(t *testing.T) {
+	tg := prepareGateway(t)
+	_, err := tg.dial(t, "hunter2")
+	assert.NotNil(t, err)
+}
+
+func TestShell(t *testing.T) {
+	tg := prepareGateway(t)
+	sess := tg.session(t)
+
+	stdin, err := sess.StdinPipe()
+	require.Nil(t, err)
+	stdout, err := sess.StdoutPipe()
+	require.Nil(t, err)
+	stderr := &bytes.Buffer{}
+	sess.Stderr = stderr
+	require.Nil(t, sess.RequestPty("xterm", 30, 100, ssh.TerminalModes{}))
+	require.Nil(t, sess.Shell())
+
+	assert.Eventually(t, func() bool {
+		rows, cols := tg.fake.ShellSize(tg.playID)
+		return rows == 30 && cols == 100
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = stdin.Write([]byte("ls\r"))
+	require.Nil(t, err)
+	out := make([]byte, 3)
+	_, err = io.ReadFull(stdout, out)
+	require.Nil(t, err)
+	assert.Equal(t, "ls\r", string(out))
+
+	require.Nil(t, sess.WindowChange(40, 120))
+	assert.Eventually(t, func() bool {
+		rows, cols := tg.fake.ShellSize(tg.playID)
+		return rows == 40 && cols == 120
+	}, time.Second, 10*time.Millisecond)
+
+	// Finishing the play ends the session cleanly
+	_, err = tg.client.CheckPlay(context.Background(), &client.CheckPlayRequest{ID: tg.playID})
+	require.Nil(t, err)
+	assert.Nil(t, sess.Wait())
+	assert.Contains(t, stderr.String(), "play finished: check passed")
+}
+
+func TestExec(t *testing.T) {
+	tg := prepareGateway(t)
+
+	out, err := tg.session(t).Output("echo hello")
+	require.Nil(t, err)
+	assert.Equal(t, "hello\n", string(out))
+
+	sess := tg.session(t)
+	sess.Stdin = strings.NewReader("piped\n")
+	out, err = sess.Output("cat")
+	require.Nil(t, err)
+	assert.Equal(t, "piped\n", string(out))
+
+	// Standard input left open, as from an interactive terminal, doesn't
+	// hold the command up
+	sess = tg.session(t)
+	_, err = sess.StdinPipe()
+	require.Nil(t, err)
+	out, err = sess.Output("echo hello")
+	require.Nil(t, err)
+	assert.Equal(t, "hello\n", string(out))
+
+	sess = tg.session(t)
+	sess.Stdin = bytes.NewReader(make([]byte, maxExecStdin+1))
+	stderr := &bytes.Buffer{}
+	sess.Stderr = stderr
+	err = sess.Run("cat")
+	var exit *ssh.ExitError
+	require.ErrorAs(t, err, &exit)
+	assert.Equal(t, 1, exit.ExitStatus())
+	assert.Contains(t, stderr.String(), ErrStdinTooLarge.Error())
+
+	sess = tg.session(t)
+	stderr.Reset()
+	sess.Stderr = stderr
+	err = sess.Run("kubectl get pods")
+	require.ErrorAs(t, err, &exit)
+	assert.Equal(t, 127, exit.ExitStatus())
+	assert.Contains(t, stderr.String(), "command not found")
+}
+
+func TestNoActivePlay(t *testing.T) {
+	tg := prepareGateway(t)
+	_, err := tg.client.CancelPlay(context.Background(), &client.CancelPlayRequest{ID: tg.playID})
+	require.Nil(t, err)
+
+	sess := tg.session(t)
+	stderr := &bytes.Buffer{}
+	sess.Stderr = stderr
+	err = sess.Run("echo hello")
+	var exit *ssh.ExitError
+	require.ErrorAs(t, err, &exit)
+	assert.Equal(t, 1, exit.ExitStatus())
+	assert.Contains(t, stderr.String(), "finding the active play")
+}
+
+// Drive the client side of scp -t, as scp -O would
+func scpUpload(t *testing.T, sess *ssh.Session, target, name string, content []byte) {
+	stdin, err := sess.StdinPipe()
+	require.Nil(t, err)
+	stdout, err := sess.StdoutPipe()
+	require.Nil(t, err)
+	r := bufio.NewReader(stdout)
+	require.Nil(t, sess.Start("scp -t "+target))
+
+	require.True(t, acked(r))
+	fmt.Fprintf(stdin, "C0600 %d %s\n", len(content), name)
+	require.True(t, acked(r))
+	stdin.Write(content)
+	stdin.Write([]byte{0})
+	require.True(t, acked(r))
+	stdin.Close()
+	require.Nil(t, sess.Wait())
+}
+
+func TestScp(t *testing.T) {
+	tg := prepareGateway(t)
+	content := []byte("server {\n  listen 80;\n}\n")
+
+	scpUpload(t, tg.session(t), "/etc/nginx/", "nginx.conf", content)
+
+	buf := &bytes.Buffer{}
+	resp, err := tg.client.DownloadFile(context.Background(), &client.DownloadFileRequest{ID: tg.playID, Path: "/etc/nginx/nginx.conf"}, buf)
+	require.Nil(t, err)
+	assert.Equal(t, content, buf.Bytes())
+	assert.Equal(t, "-rw-------", resp.Mode.String())
+
+	sess := tg.session(t)
+	stdin, err := sess.StdinPipe()
+	require.Nil(t, err)
+	stdout, err := sess.StdoutPipe()
+	require.Nil(t, err)
+	r := bufio.NewReader(stdout)
+	require.Nil(t, sess.Start("scp -f /etc/nginx/nginx.conf"))
+
+	stdin.Write([]byte{0})
+	header, err := r.ReadString('\n')
+	require.Nil(t, err)
+	assert.Equal(t, fmt.Sprintf("C0600 %d nginx.conf\n", len(content)), header)
+	stdin.Write([]byte{0})
+	got := make([]byte, len(content)+1)
+	_, err = io.ReadFull(r, got)
+	require.Nil(t, err)
+	assert.Equal(t, append(content, 0), got)
+	stdin.Write([]byte{0})
+	require.Nil(t, sess.Wait())
+}
+
+func TestScpRejectsDirectories(t *testing.T) {
+	tg := prepareGateway(t)
+	sess := tg.session(t)
+	stdin, err := sess.StdinPipe()
+	require.Nil(t, err)
+	stdout, err := sess.StdoutPipe()
+	require.Nil(t, err)
+	r := bufio.NewReader(stdout)
+	require.Nil(t, sess.Start("scp -r -t /srv"))
+
+	require.True(t, acked(r))
+	fmt.Fprint(stdin, "D0755 0 www\n")
+	msg, err := r.ReadString('\n')
+	require.Nil(t, err)
+	assert.True(t, strings.HasPrefix(msg, "\x01scp: directories are not supported"))
+	assert.NotNil(t, sess.Wait())
+}
+
+func TestScpArgs(t *testing.T) {
+	tests := []struct {
+		command string
+		ok      bool
+		want    *scpCommand
+	}{
+		{"scp -t /tmp", true, &scpCommand{sink: true, paths: []string{"/tmp"}}},
+		{"scp -v -d -t -- /tmp", true, &scpCommand{sink: true, dir: true, paths: []string{"/tmp"}}},
+		{"scp -pf /a /b", true, &scpCommand{paths: []string{"/a", "/b"}}},
+		{"scp /a /b", false, nil},
+		{"ls -t /tmp", false, nil},
+		{"", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			got, ok := scpArgs(tt.command)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
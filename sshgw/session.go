@@ -0,0 +1,282 @@
+package sshgw
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/srepio/sdk/client"
+	"golang.org/x/crypto/ssh"
+)
+
+// Exit status reported for commands killed by the exec timeout, the same
+// as timeout(1)
+const timedOutStatus = 124
+
+const (
+	// How long an exec waits for the client to start piping standard input
+	// before it runs without any
+	stdinWait = 100 * time.Millisecond
+	// The most standard input an exec takes, the exec API needs all of it up
+	// front so it is held in memory
+	maxExecStdin = 1 << 20
+)
+
+type ptyRequest struct {
+	Term   string
+	Cols   uint32
+	Rows   uint32
+	Width  uint32
+	Height uint32
+	Modes  string
+}
+
+type windowChange struct {
+	Cols   uint32
+	Rows   uint32
+	Width  uint32
+	Height uint32
+}
+
+type execRequest struct {
+	Command string
+}
+
+type exitStatus struct {
+	Status uint32
+}
+
+// A session channel, which runs at most one shell or command
+type session struct {
+	srv  *Server
+	meta ssh.ConnMetadata
+	ch   ssh.Channel
+
+	mu      *sync.Mutex
+	rows    uint16
+	cols    uint16
+	pty     bool
+	shell   *client.ShellSession
+	started bool
+}
+
+func (s *session) serve(ctx context.Context, requests <-chan *ssh.Request) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for req := range requests {
+		switch req.Type {
+		case "pty-req":
+			var pty ptyRequest
+			if err := ssh.Unmarshal(req.Payload, &pty); err != nil {
+				req.Reply(false, nil)
+				continue
+			}
+			s.mu.Lock()
+			s.rows, s.cols = clamp(pty.Rows), clamp(pty.Cols)
+			s.pty = true
+			s.mu.Unlock()
+			req.Reply(true, nil)
+		case "window-change":
+			var wc windowChange
+			if err := ssh.Unmarshal(req.Payload, &wc); err != nil {
+				continue
+			}
+			s.resize(clamp(wc.Rows), clamp(wc.Cols))
+		case "shell":
+			if !s.start() {
+				req.Reply(false, nil)
+				continue
+			}
+			req.Reply(true, nil)
+			go func() { s.exit(s.runShell(ctx)) }()
+		case "exec":
+			var ex execRequest
+			if err := ssh.Unmarshal(req.Payload, &ex); err != nil || !s.start() {
+				req.Reply(false, nil)
+				continue
+			}
+			req.Reply(true, nil)
+			go func() { s.exit(s.runCommand(ctx, ex.Command)) }()
+		default:
+			// Environment variables, signals, subsystems such as sftp and
+			// agent forwarding have nothing to map to on a play
+			if req.WantReply {
+				req.Reply(false, nil)
+			}
+		}
+	}
+}
+
+// Claim the session for a shell or command, only the first request wins
+func (s *session) start() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return false
+	}
+	s.started = true
+	return true
+}
+
+func (s *session) resize(rows, cols uint16) {
+	s.mu.Lock()
+	s.rows, s.cols = rows, cols
+	shell := s.shell
+	s.mu.Unlock()
+
+	if shell != nil {
+		shell.Resize(rows, cols)
+	}
+}
+
+// Report the exit status and close the channel
+func (s *session) exit(status int) {
+	s.ch.SendRequest("exit-status", false, ssh.Marshal(&exitStatus{Status: uint32(status)}))
+	s.ch.Close()
+}
+
+// Print an error for the client and return the status to exit with
+func (s *session) fail(err error) int {
+	fmt.Fprintf(s.ch.Stderr(), "%v\r\n", err)
+	return 1
+}
+
+// Bridge the channel to the play's shell until either side ends
+func (s *session) runShell(ctx context.Context) int {
+	id, err := s.srv.play(ctx, s.meta)
+	if err != nil {
+		return s.fail(err)
+	}
+
+	opts := &client.ShellOptions{}
+	if s.srv.opts.Shell != nil {
+		*opts = *s.srv.opts.Shell
+	}
+	s.mu.Lock()
+	req := &client.GetShellRequest{ID: id, Rows: s.rows, Cols: s.cols}
+	s.mu.Unlock()
+
+	shell, err := s.srv.api.OpenShell(ctx, req, opts)
+	if err != nil {
+		return s.fail(err)
+	}
+	defer shell.Close()
+
+	s.mu.Lock()
+	s.shell = shell
+	rows, cols := s.rows, s.cols
+	s.mu.Unlock()
+	// Catch up with window changes sent while the shell was opening
+	shell.Resize(rows, cols)
+
+	// The client closing stdin ends the session, as it does for GetShell
+	go func() {
+		io.Copy(shell, s.ch)
+		shell.Close()
+	}()
+
+	for data := range shell.Output() {
+		if _, err := s.ch.Write(data); err != nil {
+			shell.Close()
+		}
+	}
+
+	if fin := shell.Finished(); fin != nil {
+		fmt.Fprintf(s.ch.Stderr(), "\r\nplay finished: %s\r\n", fin.Reason)
+		return 0
+	}
+	if err := shell.Err(); err != nil && ctx.Err() == nil {
+		return s.fail(err)
+	}
+	return 0
+}
+
+// Run a command from ssh host 'command'. scp is handled by the gateway and
+// anything else runs through the exec API with whatever the client pipes in.
+func (s *session) runCommand(ctx context.Context, command string) int {
+	id, err := s.srv.play(ctx, s.meta)
+	if err != nil {
+		return s.fail(err)
+	}
+
+	if args, ok := scpArgs(command); ok {
+		return s.scp(ctx, id, args)
+	}
+
+	stdin, err := s.stdin(ctx)
+	if err != nil {
+		return s.fail(err)
+	}
+	resp, err := s.srv.api.Exec(ctx, &client.ExecRequest{ID: id, Command: command, Stdin: stdin})
+	if err != nil {
+		return s.fail(err)
+	}
+	s.ch.Write(resp.Stdout)
+	s.ch.Stderr().Write(resp.Stderr)
+	if resp.TimedOut {
+		return timedOutStatus
+	}
+	return resp.ExitCode
+}
+
+// Collect piped standard input for an exec. Nothing is read when a pty was
+// requested, and a client that sends nothing within stdinWait is taken to
+// have nothing to pipe, so commands run from an interactive terminal don't
+// wait for an EOF that never comes.
+func (s *session) stdin(ctx context.Context) ([]byte, error) {
+	s.mu.Lock()
+	pty := s.pty
+	s.mu.Unlock()
+	if pty {
+		return nil, nil
+	}
+
+	type read struct {
+		data []byte
+		err  error
+	}
+	first := make(chan read, 1)
+	go func() {
+		p := make([]byte, 32*1024)
+		n, err := s.ch.Read(p)
+		first <- read{data: p[:n], err: err}
+	}()
+
+	var r read
+	select {
+	case r = <-first:
+	case <-time.After(stdinWait):
+		return nil, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if errors.Is(r.err, io.EOF) {
+		return r.data, nil
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	rest, err := io.ReadAll(io.LimitReader(s.ch, int64(maxExecStdin-len(r.data)+1)))
+	if err != nil {
+		return nil, err
+	}
+	data := append(r.data, rest...)
+	if len(data) > maxExecStdin {
+		return nil, ErrStdinTooLarge
+	}
+	return data, nil
+}
+
+// Terminal sizes are sent as uint32 but the shell takes uint16
+func clamp(n uint32) uint16 {
+	if n > 0xffff {
+		return 0xffff
+	}
+	return uint16(n)
+}
@@ -0,0 +1,52 @@
+package types
+
+import "errors"
+
+// Port forwarding events. Many connections share one socket, each is a
+// stream opened by the client.
+const (
+	// Opens a stream to a port inside the play
+	ForwardOpen MessgaeType = "forward_open"
+	ForwardData MessgaeType = "forward_data"
+	// Sent by either side when it has nothing more to send on a stream, or
+	// with an error when the stream failed
+	ForwardClose MessgaeType = "forward_close"
+)
+
+type ForwardOpenPayload struct {
+	Stream uint32 `json:"stream"`
+	Port   uint16 `json:"port"`
+}
+
+func (ForwardOpenPayload) Type() MessgaeType { return ForwardOpen }
+
+func (p ForwardOpenPayload) Validate() error {
+	if p.Port == 0 {
+		return errors.New("port is required")
+	}
+	return nil
+}
+
+type ForwardDataPayload struct {
+	Stream uint32 `json:"stream"`
+	Data   []byte `json:"data"`
+}
+
+func (ForwardDataPayload) Type() MessgaeType { return ForwardData }
+
+func (p ForwardDataPayload) Validate() error { return nil }
+
+type ForwardClosePayload struct {
+	Stream uint32 `json:"stream"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (ForwardClosePayload) Type() MessgaeType { return ForwardClose }
+
+func (p ForwardClosePayload) Validate() error { return nil }
+
+func init() {
+	Register(ForwardOpen, JSONCodec[ForwardOpenPayload]())
+	Register(ForwardData, JSONCodec[ForwardDataPayload]())
+	Register(ForwardClose, JSONCodec[ForwardClosePayload]())
+}
@@ -0,0 +1,186 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The typed content of a socket event
+type Payload interface {
+	// The type of event that carries the payload
+	Type() MessgaeType
+	Validate() error
+}
+
+// Keystrokes sent to the play's terminal
+type InputPayload struct {
+	Data []byte
+}
+
+func (InputPayload) Type() MessgaeType { return Input }
+
+func (p InputPayload) Validate() error { return nil }
+
+// Output from the play's terminal
+type OutputPayload struct {
+	Data []byte
+}
+
+func (OutputPayload) Type() MessgaeType { return Output }
+
+func (p OutputPayload) Validate() error { return nil }
+
+// A new size for the play's terminal, sent as <rows>,<cols>
+type ResizePayload struct {
+	Rows uint16
+	Cols uint16
+}
+
+func (ResizePayload) Type() MessgaeType { return Resize }
+
+func (p ResizePayload) Validate() error {
+	if p.Rows == 0 || p.Cols == 0 {
+		return fmt.Errorf("invalid terminal size %dx%d", p.Rows, p.Cols)
+	}
+	return nil
+}
+
+// A heartbeat, the data is echoed back in the pong
+type PingPayload struct {
+	Data string
+}
+
+func (PingPayload) Type() MessgaeType { return Ping }
+
+func (p PingPayload) Validate() error { return nil }
+
+type PongPayload struct {
+	Data string
+}
+
+func (PongPayload) Type() MessgaeType { return Pong }
+
+func (p PongPayload) Validate() error { return nil }
+
+// The play the socket is attached to, sent as the play's JSON
+type ActivePlayPayload struct {
+	Play
+}
+
+func (ActivePlayPayload) Type() MessgaeType { return ActivePlay }
+
+func (p ActivePlayPayload) Validate() error {
+	if p.ID == "" {
+		return errors.New("play has no id")
+	}
+	return nil
+}
+
+// How the play finished
+type PlayFinishedPayload struct {
+	Status PlayStatus `json:"status"`
+	// Why the play finished, when the server gives a reason
+	Reason string `json:"reason,omitempty"`
+}
+
+// The content of a PlayFinished event, kept for code written before the
+// payload registry.
+//
+// Deprecated: use PlayFinishedPayload.
+type PlayFinishedEvent = PlayFinishedPayload
+
+func (PlayFinishedPayload) Type() MessgaeType { return PlayFinished }
+
+func (p PlayFinishedPayload) Validate() error {
+	if p.Status == "" {
+		return errors.New("play finished without a status")
+	}
+	return nil
+}
+
+// A codec for payloads that are carried in the content as is
+func rawCodec[T Payload](content func(T) string, payload func(string) Payload) Codec {
+	return CodecFuncs{
+		EncodeFunc: func(p Payload) (string, error) {
+			v, ok := asPayload[T](p)
+			if !ok {
+				return "", unexpected(p)
+			}
+			return content(v), nil
+		},
+		DecodeFunc: func(content string) (Payload, error) {
+			return payload(content), nil
+		},
+	}
+}
+
+var resizeCodec = CodecFuncs{
+	EncodeFunc: func(p Payload) (string, error) {
+		r, ok := asPayload[ResizePayload](p)
+		if !ok {
+			return "", unexpected(p)
+		}
+		return fmt.Sprintf("%d,%d", r.Rows, r.Cols), nil
+	},
+	DecodeFunc: func(content string) (Payload, error) {
+		rows, cols, ok := strings.Cut(content, ",")
+		if !ok {
+			return nil, fmt.Errorf("resize %q is not <rows>,<cols>", content)
+		}
+		r, err := strconv.ParseUint(strings.TrimSpace(rows), 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("resize rows: %w", err)
+		}
+		c, err := strconv.ParseUint(strings.TrimSpace(cols), 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("resize cols: %w", err)
+		}
+		return &ResizePayload{Rows: uint16(r), Cols: uint16(c)}, nil
+	},
+}
+
+// Accept payloads by value or pointer
+func asPayload[T Payload](p Payload) (T, bool) {
+	switch v := any(p).(type) {
+	case T:
+		return v, true
+	case *T:
+		if v != nil {
+			return *v, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+func unexpected(p Payload) error {
+	return fmt.Errorf("%w: %T", ErrUnexpectedPayload, p)
+}
+
+func init() {
+	Register(Input, rawCodec(func(p InputPayload) string {
+		return string(p.Data)
+	}, func(content string) Payload {
+		return &InputPayload{Data: []byte(content)}
+	}))
+	Register(Output, rawCodec(func(p OutputPayload) string {
+		return string(p.Data)
+	}, func(content string) Payload {
+		return &OutputPayload{Data: []byte(content)}
+	}))
+	Register(Resize, resizeCodec)
+	Register(Ping, rawCodec(func(p PingPayload) string {
+		return p.Data
+	}, func(content string) Payload {
+		return &PingPayload{Data: content}
+	}))
+	Register(Pong, rawCodec(func(p PongPayload) string {
+		return p.Data
+	}, func(content string) Payload {
+		return &PongPayload{Data: content}
+	}))
+	Register(ActivePlay, JSONCodec[ActivePlayPayload]())
+	Register(PlayFinished, JSONCodec[PlayFinishedPayload]())
+}
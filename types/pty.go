@@ -1,5 +1,7 @@
 package types
 
+import "encoding/base64"
+
 type MessgaeType string
 
 const (
@@ -16,7 +18,48 @@ const (
 	PlayFinished MessgaeType = "play_finished"
 )
 
+// How the content of a socket event is encoded
+type Encoding string
+
+const (
+	// Content is sent as is, so it must be valid UTF-8 to survive JSON
+	EncodingText Encoding = ""
+	// Content is base64 encoded and may hold arbitrary bytes
+	EncodingBase64 Encoding = "base64"
+)
+
+// The websocket subprotocol offered by clients that understand base64
+// encoded events, servers that select it base64 encode Output events and
+// accept base64 encoded Input events
+const Base64Protocol = "srep.base64"
+
 type SocketEvent struct {
-	Type    MessgaeType `json:"type"`
-	Content string      `json:"content"`
+	Type     MessgaeType `json:"type"`
+	Content  string      `json:"content"`
+	Encoding Encoding    `json:"encoding,omitempty"`
+	// The terminal the event belongs to, empty for the terminal the socket
+	// was opened for
+	Terminal string `json:"terminal,omitempty"`
+}
+
+// Encode the content as base64
+func (e *SocketEvent) EncodeBase64() {
+	if e.Encoding == EncodingBase64 {
+		return
+	}
+	e.Content = base64.StdEncoding.EncodeToString([]byte(e.Content))
+	e.Encoding = EncodingBase64
+}
+
+// Decode base64 content back to its raw bytes
+func (e *SocketEvent) Decode() error {
+	if e.Encoding != EncodingBase64 {
+		return nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(e.Content)
+	if err != nil {
+		return err
+	}
+	e.Content, e.Encoding = string(raw), EncodingText
+	return nil
 }
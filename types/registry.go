@@ -0,0 +1,161 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	ErrUnknownEvent      = errors.New("unknown event type")
+	ErrUnexpectedPayload = errors.New("unexpected payload")
+)
+
+// Converts a payload to and from the content of a socket event
+type Codec interface {
+	Encode(p Payload) (string, error)
+	Decode(content string) (Payload, error)
+}
+
+// A Codec made from a pair of funcs
+type CodecFuncs struct {
+	EncodeFunc func(p Payload) (string, error)
+	DecodeFunc func(content string) (Payload, error)
+}
+
+func (c CodecFuncs) Encode(p Payload) (string, error) {
+	return c.EncodeFunc(p)
+}
+
+func (c CodecFuncs) Decode(content string) (Payload, error) {
+	return c.DecodeFunc(content)
+}
+
+type jsonCodec[T Payload] struct{}
+
+// A Codec that carries payloads of type T as JSON, decoded payloads are *T
+func JSONCodec[T Payload]() Codec {
+	return jsonCodec[T]{}
+}
+
+func (jsonCodec[T]) Encode(p Payload) (string, error) {
+	v, ok := asPayload[T](p)
+	if !ok {
+		return "", unexpected(p)
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+func (jsonCodec[T]) Decode(content string) (Payload, error) {
+	v := new(T)
+	if err := json.Unmarshal([]byte(content), v); err != nil {
+		return nil, err
+	}
+	return any(v).(Payload), nil
+}
+
+// Maps event types to the codecs for their payloads
+type Registry struct {
+	mu     *sync.RWMutex
+	codecs map[MessgaeType]Codec
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		mu:     &sync.RWMutex{},
+		codecs: map[MessgaeType]Codec{},
+	}
+}
+
+// The registry used by the package level helpers, it has codecs for every
+// event type in this package
+var DefaultRegistry = NewRegistry()
+
+// Set the codec for an event type, replacing any that was registered before
+func (r *Registry) Register(t MessgaeType, c Codec) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.codecs[t] = c
+}
+
+func (r *Registry) codec(t MessgaeType) (Codec, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.codecs[t]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEvent, t)
+	}
+	return c, nil
+}
+
+// Validate the payload and build the event that carries it
+func (r *Registry) Encode(p Payload) (*SocketEvent, error) {
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", p.Type(), err)
+	}
+	c, err := r.codec(p.Type())
+	if err != nil {
+		return nil, err
+	}
+	content, err := c.Encode(p)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p.Type(), err)
+	}
+	return &SocketEvent{Type: p.Type(), Content: content}, nil
+}
+
+// Decode and validate the payload carried by the event, base64 content is
+// decoded first
+func (r *Registry) Decode(ev *SocketEvent) (Payload, error) {
+	c, err := r.codec(ev.Type)
+	if err != nil {
+		return nil, err
+	}
+	raw := *ev
+	if err := raw.Decode(); err != nil {
+		return nil, fmt.Errorf("%s: %w", ev.Type, err)
+	}
+	p, err := c.Decode(raw.Content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", ev.Type, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", ev.Type, err)
+	}
+	return p, nil
+}
+
+// Register a codec with the DefaultRegistry
+func Register(t MessgaeType, c Codec) {
+	DefaultRegistry.Register(t, c)
+}
+
+// Encode a payload with the DefaultRegistry
+func EncodeEvent(p Payload) (*SocketEvent, error) {
+	return DefaultRegistry.Encode(p)
+}
+
+// Decode an event's payload with the DefaultRegistry
+func DecodeEvent(ev *SocketEvent) (Payload, error) {
+	return DefaultRegistry.Decode(ev)
+}
+
+// Decode an event's payload with the DefaultRegistry as a *T
+func DecodeAs[T Payload](ev *SocketEvent) (*T, error) {
+	p, err := DecodeEvent(ev)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := any(p).(*T)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrUnexpectedPayload, p)
+	}
+	return v, nil
+}
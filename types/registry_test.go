@@ -0,0 +1,103 @@
+package types
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayloadsRoundTrip(t *testing.T) {
+	payloads := []Payload{
+		&InputPayload{Data: []byte("ls\n")},
+		&OutputPayload{Data: []byte{0xff, 0x00}},
+		&ResizePayload{Rows: 24, Cols: 80},
+		&PingPayload{Data: "1"},
+		&PongPayload{Data: "1"},
+		&ActivePlayPayload{Play: Play{ID: "bongo", Status: PlayRunning}},
+		&PlayFinishedPayload{Status: PlayCompleted, Reason: "check passed"},
+		&ForwardOpenPayload{Stream: 1, Port: 8080},
+		&ForwardDataPayload{Stream: 1, Data: []byte{0xff, 0x00}},
+		&ForwardClosePayload{Stream: 1, Error: "connection refused"},
+		&TerminalOpenPayload{Rows: 24, Cols: 80},
+		&TerminalDetachPayload{},
+		&TerminalClosePayload{Reason: "exited"},
+		&RejectedPayload{Event: Input, Reason: "spectators are read-only"},
+	}
+
+	for _, p := range payloads {
+		ev, err := EncodeEvent(p)
+		require.Nil(t, err, "%T", p)
+		assert.Equal(t, p.Type(), ev.Type)
+
+		decoded, err := DecodeEvent(ev)
+		require.Nil(t, err, "%T", p)
+		assert.Equal(t, p, decoded)
+	}
+}
+
+func TestResizeIsSentAsRowsAndCols(t *testing.T) {
+	ev, err := EncodeEvent(ResizePayload{Rows: 24, Cols: 80})
+	require.Nil(t, err)
+	assert.Equal(t, "24,80", ev.Content)
+
+	for _, content := range []string{"", "24", "24,", "a,80", "24,70000", "0,80"} {
+		_, err := DecodeEvent(&SocketEvent{Type: Resize, Content: content})
+		assert.Error(t, err, "%q", content)
+	}
+
+	_, err = EncodeEvent(&ResizePayload{Rows: 24})
+	assert.Error(t, err)
+}
+
+func TestDecodeHandlesBase64(t *testing.T) {
+	ev := &SocketEvent{Type: Output, Content: "\xff\xfe"}
+	ev.EncodeBase64()
+	assert.Equal(t, EncodingBase64, ev.Encoding)
+
+	out, err := DecodeAs[OutputPayload](ev)
+	require.Nil(t, err)
+	assert.Equal(t, []byte{0xff, 0xfe}, out.Data)
+}
+
+func TestUnknownEvents(t *testing.T) {
+	_, err := DecodeEvent(&SocketEvent{Type: "bongo"})
+	assert.ErrorIs(t, err, ErrUnknownEvent)
+
+	_, err = DecodeAs[InputPayload](&SocketEvent{Type: Output})
+	assert.ErrorIs(t, err, ErrUnexpectedPayload)
+}
+
+type notePayload struct {
+	Text string `json:"text"`
+}
+
+func (notePayload) Type() MessgaeType { return "note" }
+
+func (p notePayload) Validate() error {
+	if p.Text == "" {
+		return errors.New("empty note")
+	}
+	return nil
+}
+
+func TestCustomCodecs(t *testing.T) {
+	r := NewRegistry()
+	r.Register("note", JSONCodec[notePayload]())
+
+	ev, err := r.Encode(notePayload{Text: "hi"})
+	require.Nil(t, err)
+	assert.Equal(t, `{"text":"hi"}`, ev.Content)
+
+	p, err := r.Decode(ev)
+	require.Nil(t, err)
+	assert.Equal(t, &notePayload{Text: "hi"}, p)
+
+	_, err = r.Decode(&SocketEvent{Type: "note", Content: `{"text":""}`})
+	assert.Error(t, err)
+
+	// Separate registries don't share codecs
+	_, err = DecodeEvent(ev)
+	assert.ErrorIs(t, err, ErrUnknownEvent)
+}
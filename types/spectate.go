@@ -0,0 +1,26 @@
+package types
+
+import "errors"
+
+// Sent by the server when it refuses an event, such as input from a
+// spectator
+const Rejected MessgaeType = "rejected"
+
+type RejectedPayload struct {
+	// The type of the event that was refused
+	Event  MessgaeType `json:"event"`
+	Reason string      `json:"reason,omitempty"`
+}
+
+func (RejectedPayload) Type() MessgaeType { return Rejected }
+
+func (p RejectedPayload) Validate() error {
+	if p.Event == "" {
+		return errors.New("rejected event has no type")
+	}
+	return nil
+}
+
+func init() {
+	Register(Rejected, JSONCodec[RejectedPayload]())
+}
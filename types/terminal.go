@@ -0,0 +1,59 @@
+package types
+
+// The terminal every play starts with, events and shells that don't name a
+// terminal use it
+const DefaultTerminal = "main"
+
+// Terminal events for sockets that carry more than one terminal. Events for
+// other terminals name them in SocketEvent.Terminal.
+const (
+	// Attach the socket to the terminal, creating it if it doesn't exist
+	TerminalOpen MessgaeType = "terminal_open"
+	// Stop receiving the terminal's events on the socket, it keeps running
+	TerminalDetach MessgaeType = "terminal_detach"
+	// Sent by clients to end a terminal, and by servers to every socket
+	// attached to a terminal that has ended
+	TerminalClose MessgaeType = "terminal_close"
+)
+
+// A terminal running in a play
+type Terminal struct {
+	ID   string `json:"id"`
+	Rows uint16 `json:"rows"`
+	Cols uint16 `json:"cols"`
+	// How many sockets are attached
+	Attached int `json:"attached"`
+	// How many spectators are watching
+	Spectators int   `json:"spectators"`
+	CreatedAt  int64 `json:"created_at"`
+}
+
+type TerminalOpenPayload struct {
+	// The initial size of a new terminal, ignored when it already exists
+	Rows uint16 `json:"rows,omitempty"`
+	Cols uint16 `json:"cols,omitempty"`
+}
+
+func (TerminalOpenPayload) Type() MessgaeType { return TerminalOpen }
+
+func (p TerminalOpenPayload) Validate() error { return nil }
+
+type TerminalDetachPayload struct{}
+
+func (TerminalDetachPayload) Type() MessgaeType { return TerminalDetach }
+
+func (p TerminalDetachPayload) Validate() error { return nil }
+
+type TerminalClosePayload struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+func (TerminalClosePayload) Type() MessgaeType { return TerminalClose }
+
+func (p TerminalClosePayload) Validate() error { return nil }
+
+func init() {
+	Register(TerminalOpen, JSONCodec[TerminalOpenPayload]())
+	Register(TerminalDetach, JSONCodec[TerminalDetachPayload]())
+	Register(TerminalClose, JSONCodec[TerminalClosePayload]())
+}
@@ -0,0 +1,43 @@
+package vt
+
+// A colour, either the terminal's default, one of the 256 indexed colours or
+// 24 bit RGB
+type Color int32
+
+const DefaultColor Color = -1
+
+func Indexed(n uint8) Color {
+	return Color(n)
+}
+
+func RGB(r, g, b uint8) Color {
+	return Color(1<<24 | int32(r)<<16 | int32(g)<<8 | int32(b))
+}
+
+// Whether the colour is 24 bit rather than indexed
+func (c Color) IsRGB() bool {
+	return c >= 1<<24
+}
+
+// How a cell is drawn, set with SGR sequences
+type Attr struct {
+	FG        Color
+	BG        Color
+	Bold      bool
+	Faint     bool
+	Italic    bool
+	Underline bool
+	Blink     bool
+	Reverse   bool
+}
+
+var defaultAttr = Attr{FG: DefaultColor, BG: DefaultColor}
+
+type Cell struct {
+	Rune rune
+	Attr Attr
+}
+
+func blankCell(attr Attr) Cell {
+	return Cell{Rune: ' ', Attr: Attr{FG: DefaultColor, BG: attr.BG}}
+}
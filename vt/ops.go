@@ -0,0 +1,118 @@
+package vt
+
+func (s *Screen) print(r rune) {
+	if s.wrap && s.autowrap {
+		s.col = 0
+		s.index()
+	}
+	s.wrap = false
+	s.cells[s.row][s.col] = Cell{Rune: r, Attr: s.attr}
+	if s.col == s.cols-1 {
+		s.wrap = true
+		return
+	}
+	s.col++
+}
+
+// Move down a line, scrolling at the bottom of the scroll region
+func (s *Screen) index() {
+	s.wrap = false
+	switch {
+	case s.row == s.bottom:
+		s.scrollUp(s.top, 1)
+	case s.row < s.rows-1:
+		s.row++
+	}
+}
+
+// Move up a line, scrolling at the top of the scroll region
+func (s *Screen) reverseIndex() {
+	s.wrap = false
+	switch {
+	case s.row == s.top:
+		s.scrollDown(s.top, 1)
+	case s.row > 0:
+		s.row--
+	}
+}
+
+// Scroll the lines from a row to the bottom of the scroll region up
+func (s *Screen) scrollUp(from, n int) {
+	region := s.cells[from : s.bottom+1]
+	n = min(n, len(region))
+	lines := append(region[n:len(region):len(region)], s.blankLines(n)...)
+	copy(region, lines)
+}
+
+// Scroll the lines from a row to the bottom of the scroll region down
+func (s *Screen) scrollDown(from, n int) {
+	region := s.cells[from : s.bottom+1]
+	n = min(n, len(region))
+	lines := append(s.blankLines(n), region[:len(region)-n]...)
+	copy(region, lines)
+}
+
+func (s *Screen) moveTo(row, col int) {
+	s.row = max(0, min(row, s.rows-1))
+	s.col = max(0, min(col, s.cols-1))
+	s.wrap = false
+}
+
+func (s *Screen) clear(cells []Cell) {
+	for i := range cells {
+		cells[i] = blankCell(s.attr)
+	}
+}
+
+func (s *Screen) eraseDisplay(mode int) {
+	switch mode {
+	case 0:
+		s.clear(s.cells[s.row][s.col:])
+		for _, line := range s.cells[s.row+1:] {
+			s.clear(line)
+		}
+	case 1:
+		s.clear(s.cells[s.row][:s.col+1])
+		for _, line := range s.cells[:s.row] {
+			s.clear(line)
+		}
+	case 2, 3:
+		for _, line := range s.cells {
+			s.clear(line)
+		}
+	}
+}
+
+func (s *Screen) eraseLine(mode int) {
+	line := s.cells[s.row]
+	switch mode {
+	case 0:
+		s.clear(line[s.col:])
+	case 1:
+		s.clear(line[:s.col+1])
+	case 2:
+		s.clear(line)
+	}
+}
+
+func (s *Screen) saveCursor() {
+	s.saved = cursor{row: s.row, col: s.col, attr: s.attr}
+}
+
+func (s *Screen) restoreCursor() {
+	s.moveTo(s.saved.row, s.saved.col)
+	s.attr = s.saved.attr
+}
+
+func (s *Screen) reset() {
+	s.attr = defaultAttr
+	s.primary = nil
+	s.cells = s.blankLines(s.rows)
+	s.row, s.col = 0, 0
+	s.wrap = false
+	s.autowrap = true
+	s.hidden = false
+	s.top, s.bottom = 0, s.rows-1
+	s.saved = cursor{attr: defaultAttr}
+	s.title = ""
+}
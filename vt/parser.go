@@ -0,0 +1,313 @@
+package vt
+
+import (
+	"strconv"
+	"strings"
+)
+
+func (s *Screen) byte(b byte) {
+	switch s.state {
+	case escape:
+		s.escape(b)
+		return
+	case charset:
+		// The character set is ignored, everything is treated as UTF-8
+		s.state = ground
+		return
+	case csi:
+		s.csiByte(b)
+		return
+	case osc:
+		switch b {
+		case 0x07:
+			s.endOSC()
+		case 0x1b:
+			s.state = oscEscape
+		default:
+			s.oscBuf = append(s.oscBuf, b)
+		}
+		return
+	case oscEscape:
+		// ESC \ ends the sequence, anything else abandons it
+		if b == '\\' {
+			s.endOSC()
+			return
+		}
+		s.state = ground
+		s.escape(b)
+		return
+	}
+
+	switch b {
+	case 0x1b:
+		s.state = escape
+	case '\r':
+		s.col = 0
+		s.wrap = false
+	case '\n', 0x0b, 0x0c:
+		s.index()
+	case '\b':
+		if s.col > 0 {
+			s.col--
+		}
+		s.wrap = false
+	case '\t':
+		s.col = min((s.col/tabWidth+1)*tabWidth, s.cols-1)
+		s.wrap = false
+	default:
+		if b >= 0x20 && b != 0x7f {
+			s.print(rune(b))
+		}
+	}
+}
+
+func (s *Screen) escape(b byte) {
+	s.state = ground
+	switch b {
+	case '[':
+		s.state = csi
+		s.params = s.params[:0]
+		s.private = 0
+	case ']':
+		s.state = osc
+		s.oscBuf = s.oscBuf[:0]
+	case '(', ')', '*', '+':
+		s.state = charset
+	case '7':
+		s.saveCursor()
+	case '8':
+		s.restoreCursor()
+	case 'D':
+		s.index()
+	case 'E':
+		s.col = 0
+		s.index()
+	case 'M':
+		s.reverseIndex()
+	case 'c':
+		s.reset()
+	}
+}
+
+func (s *Screen) csiByte(b byte) {
+	switch {
+	case b == '?' || b == '>' || b == '=':
+		s.private = b
+	case b >= '0' && b <= '9' || b == ';' || b == ':':
+		s.params = append(s.params, b)
+	case b >= 0x40 && b <= 0x7e:
+		s.state = ground
+		s.dispatch(b, parseParams(string(s.params)))
+	case b == 0x18 || b == 0x1a:
+		// CAN and SUB abandon the sequence
+		s.state = ground
+	}
+}
+
+func parseParams(raw string) []int {
+	if raw == "" {
+		return nil
+	}
+	parts := strings.FieldsFunc(raw, func(r rune) bool { return r == ';' || r == ':' })
+	out := make([]int, 0, len(parts))
+	for _, p := range parts {
+		n, _ := strconv.Atoi(p)
+		out = append(out, n)
+	}
+	return out
+}
+
+// The nth parameter, or def when it is missing or zero
+func param(params []int, n, def int) int {
+	if n < len(params) && params[n] != 0 {
+		return params[n]
+	}
+	return def
+}
+
+func (s *Screen) dispatch(final byte, params []int) {
+	if s.private == '?' {
+		switch final {
+		case 'h':
+			s.setModes(params, true)
+		case 'l':
+			s.setModes(params, false)
+		}
+		return
+	}
+	if s.private != 0 {
+		return
+	}
+
+	n := param(params, 0, 1)
+	switch final {
+	case 'A':
+		s.moveTo(s.row-n, s.col)
+	case 'B', 'e':
+		s.moveTo(s.row+n, s.col)
+	case 'C', 'a':
+		s.moveTo(s.row, s.col+n)
+	case 'D':
+		s.moveTo(s.row, s.col-n)
+	case 'E':
+		s.moveTo(s.row+n, 0)
+	case 'F':
+		s.moveTo(s.row-n, 0)
+	case 'G', '`':
+		s.moveTo(s.row, n-1)
+	case 'd':
+		s.moveTo(n-1, s.col)
+	case 'H', 'f':
+		s.moveTo(param(params, 0, 1)-1, param(params, 1, 1)-1)
+	case 'J':
+		s.eraseDisplay(param(params, 0, 0))
+	case 'K':
+		s.eraseLine(param(params, 0, 0))
+	case 'L':
+		if s.row >= s.top && s.row <= s.bottom {
+			s.scrollDown(s.row, n)
+		}
+	case 'M':
+		if s.row >= s.top && s.row <= s.bottom {
+			s.scrollUp(s.row, n)
+		}
+	case 'S':
+		s.scrollUp(s.top, n)
+	case 'T':
+		s.scrollDown(s.top, n)
+	case '@':
+		line := s.cells[s.row]
+		n = min(n, s.cols-s.col)
+		copy(line[s.col+n:], line[s.col:])
+		s.clear(line[s.col : s.col+n])
+	case 'P':
+		line := s.cells[s.row]
+		n = min(n, s.cols-s.col)
+		copy(line[s.col:], line[s.col+n:])
+		s.clear(line[s.cols-n:])
+	case 'X':
+		s.clear(s.cells[s.row][s.col:min(s.col+n, s.cols)])
+	case 'm':
+		s.sgr(params)
+	case 'r':
+		top, bottom := param(params, 0, 1)-1, param(params, 1, s.rows)-1
+		if top < bottom && bottom < s.rows {
+			s.top, s.bottom = top, bottom
+			s.moveTo(0, 0)
+		}
+	case 's':
+		s.saveCursor()
+	case 'u':
+		s.restoreCursor()
+	}
+}
+
+func (s *Screen) setModes(params []int, on bool) {
+	for _, mode := range params {
+		switch mode {
+		case 7:
+			s.autowrap = on
+		case 25:
+			s.hidden = !on
+		case 47, 1047:
+			s.alternate(on)
+		case 1049:
+			if on {
+				s.saveCursor()
+				s.alternate(true)
+			} else {
+				s.alternate(false)
+				s.restoreCursor()
+			}
+		}
+	}
+}
+
+// Switch to or from the alternate screen used by full screen programs
+func (s *Screen) alternate(on bool) {
+	switch {
+	case on && s.primary == nil:
+		s.primary = s.cells
+		s.cells = s.blankLines(s.rows)
+	case !on && s.primary != nil:
+		s.cells = s.primary
+		s.primary = nil
+	}
+}
+
+func (s *Screen) sgr(params []int) {
+	if len(params) == 0 {
+		params = []int{0}
+	}
+	for i := 0; i < len(params); i++ {
+		switch p := params[i]; {
+		case p == 0:
+			s.attr = defaultAttr
+		case p == 1:
+			s.attr.Bold = true
+		case p == 2:
+			s.attr.Faint = true
+		case p == 3:
+			s.attr.Italic = true
+		case p == 4:
+			s.attr.Underline = true
+		case p == 5:
+			s.attr.Blink = true
+		case p == 7:
+			s.attr.Reverse = true
+		case p == 22:
+			s.attr.Bold, s.attr.Faint = false, false
+		case p == 23:
+			s.attr.Italic = false
+		case p == 24:
+			s.attr.Underline = false
+		case p == 25:
+			s.attr.Blink = false
+		case p == 27:
+			s.attr.Reverse = false
+		case p >= 30 && p <= 37:
+			s.attr.FG = Indexed(uint8(p - 30))
+		case p == 39:
+			s.attr.FG = DefaultColor
+		case p >= 40 && p <= 47:
+			s.attr.BG = Indexed(uint8(p - 40))
+		case p == 49:
+			s.attr.BG = DefaultColor
+		case p >= 90 && p <= 97:
+			s.attr.FG = Indexed(uint8(p - 90 + 8))
+		case p >= 100 && p <= 107:
+			s.attr.BG = Indexed(uint8(p - 100 + 8))
+		case p == 38 || p == 48:
+			c, used := extendedColor(params[i+1:])
+			i += used
+			if used == 0 {
+				continue
+			}
+			if p == 38 {
+				s.attr.FG = c
+			} else {
+				s.attr.BG = c
+			}
+		}
+	}
+}
+
+// Read a 5;n or 2;r;g;b colour, returning how many parameters it used
+func extendedColor(params []int) (Color, int) {
+	switch {
+	case len(params) >= 2 && params[0] == 5:
+		return Indexed(uint8(params[1])), 2
+	case len(params) >= 4 && params[0] == 2:
+		return RGB(uint8(params[1]), uint8(params[2]), uint8(params[3])), 4
+	}
+	return DefaultColor, 0
+}
+
+func (s *Screen) endOSC() {
+	s.state = ground
+	// 0 and 2 set the window title
+	code, text, ok := strings.Cut(string(s.oscBuf), ";")
+	if ok && (code == "0" || code == "2") {
+		s.title = text
+	}
+}
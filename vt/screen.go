@@ -0,0 +1,239 @@
+// Package vt is a headless VT100 terminal. It interprets the escape
+// sequences a shell writes and keeps the screen it would show, so tests can
+// assert on what a user would see rather than on a stream of bytes.
+package vt
+
+import (
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/srepio/sdk/client"
+	"github.com/srepio/sdk/types"
+)
+
+const tabWidth = 8
+
+type state int
+
+const (
+	ground state = iota
+	escape
+	charset
+	csi
+	osc
+	oscEscape
+)
+
+// A virtual screen. Write output to it directly or pass it to a shell as
+// ShellOptions.Recorder to follow the session's output and resizes. Every
+// rune takes up one cell, wide characters are not handled.
+type Screen struct {
+	mu *sync.Mutex
+
+	rows, cols int
+	cells      [][]Cell
+	primary    [][]Cell
+	row, col   int
+	attr       Attr
+	// Set after writing the last column, the next rune wraps first
+	wrap     bool
+	autowrap bool
+	hidden   bool
+	top      int
+	bottom   int
+	saved    cursor
+	title    string
+
+	state   state
+	params  []byte
+	private byte
+	oscBuf  []byte
+	partial []byte
+}
+
+type cursor struct {
+	row, col int
+	attr     Attr
+}
+
+var _ client.ShellRecorder = (*Screen)(nil)
+
+// A blank screen, the size defaults to 24x80
+func New(rows, cols int) *Screen {
+	if rows <= 0 {
+		rows = 24
+	}
+	if cols <= 0 {
+		cols = 80
+	}
+	s := &Screen{
+		mu:       &sync.Mutex{},
+		rows:     rows,
+		cols:     cols,
+		attr:     defaultAttr,
+		autowrap: true,
+		bottom:   rows - 1,
+	}
+	s.cells = s.blankLines(rows)
+	s.saved = cursor{attr: defaultAttr}
+	return s
+}
+
+// Follow the output and resizes of a shell session
+func (s *Screen) RecordEvent(_ time.Time, p types.Payload) {
+	switch p := p.(type) {
+	case *types.OutputPayload:
+		s.Write(p.Data)
+	case *types.ResizePayload:
+		s.Resize(int(p.Rows), int(p.Cols))
+	}
+}
+
+// Interpret output, it never fails
+func (s *Screen) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := p
+	if len(s.partial) > 0 {
+		data = append(s.partial, p...)
+		s.partial = nil
+	}
+	for len(data) > 0 {
+		b := data[0]
+		if s.state != ground || b < utf8.RuneSelf {
+			s.byte(b)
+			data = data[1:]
+			continue
+		}
+		if !utf8.FullRune(data) {
+			s.partial = append([]byte{}, data...)
+			break
+		}
+		r, size := utf8.DecodeRune(data)
+		s.print(r)
+		data = data[size:]
+	}
+	return len(p), nil
+}
+
+func (s *Screen) Resize(rows, cols int) {
+	if rows <= 0 || cols <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resize := func(lines [][]Cell) [][]Cell {
+		if lines == nil {
+			return nil
+		}
+		out := s.blankLinesOf(rows, cols)
+		for r := 0; r < rows && r < len(lines); r++ {
+			copy(out[r], lines[r])
+		}
+		return out
+	}
+	s.cells = resize(s.cells)
+	s.primary = resize(s.primary)
+	s.rows, s.cols = rows, cols
+	s.top, s.bottom = 0, rows-1
+	s.row = min(s.row, rows-1)
+	s.col = min(s.col, cols-1)
+	s.wrap = false
+}
+
+func (s *Screen) Size() (rows, cols int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rows, s.cols
+}
+
+// Where the next rune will be written, zero based
+func (s *Screen) Cursor() (row, col int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.row, s.col
+}
+
+// Whether the cursor has been hidden
+func (s *Screen) CursorHidden() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hidden
+}
+
+// The window title last set with an OSC sequence
+func (s *Screen) Title() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.title
+}
+
+// The cell at a position, blank when it is off the screen
+func (s *Screen) Cell(row, col int) Cell {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if row < 0 || row >= s.rows || col < 0 || col >= s.cols {
+		return blankCell(defaultAttr)
+	}
+	return s.cells[row][col]
+}
+
+// A copy of every cell on the screen
+func (s *Screen) Cells() [][]Cell {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([][]Cell, s.rows)
+	for r, line := range s.cells {
+		out[r] = append([]Cell{}, line...)
+	}
+	return out
+}
+
+// The text of a line without trailing spaces
+func (s *Screen) Line(row int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if row < 0 || row >= s.rows {
+		return ""
+	}
+	return lineText(s.cells[row])
+}
+
+// A text screenshot, one line per row with trailing spaces and blank lines
+// at the bottom trimmed
+func (s *Screen) Text() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lines := make([]string, s.rows)
+	for r, line := range s.cells {
+		lines[r] = lineText(line)
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+func lineText(line []Cell) string {
+	b := &strings.Builder{}
+	for _, c := range line {
+		b.WriteRune(c.Rune)
+	}
+	return strings.TrimRight(b.String(), " ")
+}
+
+func (s *Screen) blankLines(n int) [][]Cell {
+	return s.blankLinesOf(n, s.cols)
+}
+
+func (s *Screen) blankLinesOf(n, cols int) [][]Cell {
+	lines := make([][]Cell, n)
+	for i := range lines {
+		lines[i] = make([]Cell, cols)
+		for j := range lines[i] {
+			lines[i][j] = blankCell(s.attr)
+		}
+	}
+	return lines
+}
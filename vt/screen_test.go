@@ -0,0 +1,139 @@
+package vt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/srepio/sdk/client"
+	"github.com/srepio/sdk/srepfake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func write(s *Screen, out string) {
+	s.Write([]byte(out))
+}
+
+func TestText(t *testing.T) {
+	s := New(5, 10)
+	write(s, "$ ls\r\nfile  dir\r\n$ ")
+	assert.Equal(t, "$ ls\nfile  dir\n$", s.Text())
+	row, col := s.Cursor()
+	assert.Equal(t, 2, row)
+	assert.Equal(t, 2, col)
+
+	// Lines wrap once the last column is written to
+	write(s, "0123456789ab")
+	assert.Equal(t, "$ 01234567", s.Line(2))
+	assert.Equal(t, "89ab", s.Line(3))
+}
+
+func TestScrolling(t *testing.T) {
+	s := New(3, 10)
+	write(s, "one\r\ntwo\r\nthree\r\nfour")
+	assert.Equal(t, "two\nthree\nfour", s.Text())
+
+	// Only the scroll region moves
+	write(s, "\x1b[2;3r\x1b[3;1Hfive\n")
+	assert.Equal(t, "two\nfive", s.Text())
+
+	write(s, "\x1b[r\x1b[H\x1bMzero")
+	assert.Equal(t, "zero\ntwo\nfive", s.Text())
+}
+
+func TestCursorAndErasing(t *testing.T) {
+	s := New(4, 10)
+	write(s, "hello world\x1b[1;3H\x1b[K")
+	assert.Equal(t, "he\nd", s.Text())
+
+	write(s, "\x1b[2J\x1b[3;5Hx\x1b[2D\x1b[Ay")
+	assert.Equal(t, "\n   y\n    x", s.Text())
+
+	write(s, "\x1b[1;1Habcdef\x1b[1;2H\x1b[2P\x1b[1@")
+	assert.Equal(t, "a def", s.Line(0))
+
+	write(s, "\x1b7\x1b[4;4Hz\x1b8q")
+	row, col := s.Cursor()
+	assert.Equal(t, 0, row)
+	assert.Equal(t, 2, col)
+	assert.Equal(t, "aqdef", s.Line(0))
+	assert.Equal(t, "   z", s.Line(3))
+}
+
+func TestAttributes(t *testing.T) {
+	s := New(2, 10)
+	write(s, "\x1b[1;31ma\x1b[0;38;5;200;48;2;1;2;3mb\x1b[7;94mc\x1b[mD")
+
+	assert.Equal(t, Attr{FG: Indexed(1), BG: DefaultColor, Bold: true}, s.Cell(0, 0).Attr)
+	assert.Equal(t, Attr{FG: Indexed(200), BG: RGB(1, 2, 3)}, s.Cell(0, 1).Attr)
+	assert.True(t, s.Cell(0, 1).Attr.BG.IsRGB())
+	assert.Equal(t, Attr{FG: Indexed(12), BG: RGB(1, 2, 3), Reverse: true}, s.Cell(0, 2).Attr)
+	assert.Equal(t, defaultAttr, s.Cell(0, 3).Attr)
+}
+
+func TestAlternateScreen(t *testing.T) {
+	s := New(3, 10)
+	write(s, "$ vim\r\n")
+	write(s, "\x1b[?1049h\x1b[?25l\x1b[Hediting\x1b]0;vim\x07")
+	assert.Equal(t, "editing", s.Text())
+	assert.True(t, s.CursorHidden())
+	assert.Equal(t, "vim", s.Title())
+
+	write(s, "\x1b[?1049l\x1b[?25h")
+	assert.Equal(t, "$ vim", s.Text())
+	row, col := s.Cursor()
+	assert.Equal(t, 1, row)
+	assert.Equal(t, 0, col)
+}
+
+func TestSplitSequences(t *testing.T) {
+	s := New(2, 10)
+	for _, b := range []byte("\x1b[31mé\x1b]2;t\x1b\\") {
+		write(s, string([]byte{b}))
+	}
+	assert.Equal(t, Cell{Rune: 'é', Attr: Attr{FG: Indexed(1), BG: DefaultColor}}, s.Cell(0, 0))
+	assert.Equal(t, "t", s.Title())
+}
+
+func TestResize(t *testing.T) {
+	s := New(3, 10)
+	write(s, "0123456789\r\n\r\nend")
+	s.Resize(2, 4)
+	assert.Equal(t, "0123", s.Text())
+	row, col := s.Cursor()
+	assert.Equal(t, 1, row)
+	assert.Equal(t, 3, col)
+}
+
+func TestFollowingAShell(t *testing.T) {
+	s := srepfake.New(nil)
+	defer s.Close()
+	_, token := s.NewUser("Bongo", "bongo@srep.io", "hunter2hunter2")
+	c := client.NewClient(&client.ClientOptions{
+		Url:    s.Host(),
+		Scheme: "http",
+		Token:  token,
+	})
+	ctx := context.Background()
+	started, err := c.StartPlay(ctx, &client.StartPlayRequest{Scenario: "mango"})
+	require.Nil(t, err)
+
+	screen := New(0, 0)
+	shell, err := c.OpenShell(ctx, &client.GetShellRequest{ID: started.Play.ID, Rows: 30, Cols: 100}, &client.ShellOptions{
+		Recorder: screen,
+	})
+	require.Nil(t, err)
+	defer shell.Close()
+
+	_, err = shell.Write([]byte("ls\r"))
+	require.Nil(t, err)
+	<-shell.Output()
+
+	assert.Eventually(t, func() bool {
+		return screen.Text() == "ls"
+	}, time.Second, 10*time.Millisecond)
+	rows, cols := screen.Size()
+	assert.Equal(t, 30, rows)
+	assert.Equal(t, 100, cols)
+}